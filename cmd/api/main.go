@@ -13,9 +13,11 @@ import (
 	"ecommerce-api/internal/db"
 	"ecommerce-api/internal/handlers"
 	"ecommerce-api/internal/middlewares"
+	"ecommerce-api/internal/notify"
 	"ecommerce-api/internal/repositories"
 	"ecommerce-api/internal/server"
 	"ecommerce-api/internal/services"
+	"ecommerce-api/internal/storage"
 )
 
 func main() {
@@ -37,38 +39,110 @@ func main() {
 	// Redis
 	rdb, err := db.NewRedisClient(ctx, cfg.RedisURL)
 	if err != nil {
-		log.Printf("redis unavailable: %v — cart features disabled", err)
+		log.Printf("redis unavailable: %v — product cache disabled, carts in postgres", err)
 		rdb = nil
 	} else {
 		log.Println("connected to redis")
 		defer rdb.Close()
 	}
 
+	// Хранилище файлов
+	blobStorage, err := storage.NewLocalStorage(cfg.Storage.Dir, cfg.Storage.BaseURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Уведомления
+	var notifier notify.Notifier
+	if cfg.SMTP.Host != "" {
+		notifier = notify.NewSMTPNotifier(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.From)
+	} else {
+		log.Println("SMTP_HOST is not set, notifications are only logged")
+		notifier = notify.NewLogNotifier()
+	}
+
 	// Репозитории
-	productRepo := repositories.NewProductRepository(pool)
-	cartRepo := repositories.NewCartRepository(rdb)
+	productRepo := repositories.NewCachedProductRepository(repositories.NewProductRepository(pool), rdb)
+	var cartRepo repositories.CartRepository
+	switch {
+	case cfg.Cart.Storage == config.CartStoragePostgres || rdb == nil && cfg.Cart.Storage == config.CartStorageAuto:
+		cartRepo = repositories.NewPostgresCartRepository(pool)
+	case rdb == nil:
+		log.Fatal("CART_STORAGE=redis requires a reachable redis")
+	case cfg.Cart.Storage == config.CartStorageRedis:
+		cartRepo = repositories.NewCartRepository(rdb)
+	default:
+		cartRepo = repositories.NewFailoverCartRepository(repositories.NewCartRepository(rdb), repositories.NewPostgresCartRepository(pool))
+	}
 	userRepo := repositories.NewUserRepository(pool)
 	orderRepo := repositories.NewOrderRepository(pool)
+	categoryRepo := repositories.NewCategoryRepository(pool)
+	variantRepo := repositories.NewVariantRepository(pool)
+	imageRepo := repositories.NewImageRepository(pool)
+	reviewRepo := repositories.NewReviewRepository(pool)
+	attributeRepo := repositories.NewAttributeRepository(pool)
+	priceRepo := repositories.NewPriceRepository(pool)
+	relatedRepo := repositories.NewRelatedRepository(pool)
+	wishlistRepo := repositories.NewWishlistRepository(pool)
+	cartActivityRepo := repositories.NewCartActivityRepository(pool)
+	purchaseRuleRepo := repositories.NewPurchaseRuleRepository(pool)
+	// Без Redis делиться корзинами нельзя: сервис вернёт ErrCartSharingUnavailable.
+	var cartShareRepo repositories.CartShareRepository
+	if rdb != nil {
+		cartShareRepo = repositories.NewCartShareRepository(rdb)
+	}
 
 	// Сервисы
-	productService := services.NewProductService(productRepo)
-	cartService := services.NewCartService(cartRepo, productRepo)
+	imageService := services.NewImageService(productRepo, imageRepo, blobStorage)
+	recommendationService := services.NewRecommendationService(productRepo, relatedRepo, imageService)
+	productService := services.NewProductService(productRepo, variantRepo, attributeRepo, purchaseRuleRepo, imageService)
+	cartService := services.NewCartService(cartRepo, cartActivityRepo, cartShareRepo, purchaseRuleRepo, productRepo, variantRepo, imageService, recommendationService, cfg.Cart)
 	authService := services.NewAuthService(userRepo, cfg.JWT)
 	paymentService := services.NewPaymentService(cfg.YooKassa)
-	orderService := services.NewOrderService(pool, productRepo, cartRepo, orderRepo, paymentService)
+	orderService := services.NewOrderService(pool, productRepo, variantRepo, cartRepo, orderRepo, purchaseRuleRepo, paymentService, imageService)
+	categoryService := services.NewCategoryService(categoryRepo, productRepo)
+	variantService := services.NewVariantService(productRepo, variantRepo)
+	productCSVService := services.NewProductCSVService(pool, productRepo)
+	feedService := services.NewFeedService(productRepo, categoryRepo, variantRepo, imageService, cfg.Feed)
+	exchangeService := services.NewExchangeService(pool, productRepo, orderRepo, cfg.Exchange)
+	reviewService := services.NewReviewService(productRepo, reviewRepo)
+	attributeService := services.NewAttributeService(productRepo, attributeRepo)
+	priceService := services.NewPriceService(productRepo, priceRepo)
+	wishlistService := services.NewWishlistService(wishlistRepo, productRepo, variantRepo, cartRepo, cartService, imageService)
+	cartReminderService := services.NewCartReminderService(cartActivityRepo, cartRepo, productRepo, variantRepo, notifier, cfg.CartReminder)
 
 	// Хендлеры
 	productHandler := handlers.NewProductHandler(productService)
 	cartHandler := handlers.NewCartHandler(cartService)
-	authHandler := handlers.NewAuthHandler(authService)
+	authHandler := handlers.NewAuthHandler(authService, cartService)
 	orderHandler := handlers.NewOrderHandler(orderService)
 	paymentHandler := handlers.NewPaymentHandler(orderService)
+	categoryHandler := handlers.NewCategoryHandler(categoryService)
+	variantHandler := handlers.NewVariantHandler(variantService)
+	imageHandler := handlers.NewImageHandler(imageService)
+	productCSVHandler := handlers.NewProductCSVHandler(productCSVService)
+	feedHandler := handlers.NewFeedHandler(feedService)
+	exchangeHandler := handlers.NewExchangeHandler(exchangeService)
+	reviewHandler := handlers.NewReviewHandler(reviewService)
+	attributeHandler := handlers.NewAttributeHandler(attributeService)
+	priceHandler := handlers.NewPriceHandler(priceService)
+	recommendationHandler := handlers.NewRecommendationHandler(recommendationService)
+	wishlistHandler := handlers.NewWishlistHandler(wishlistService)
+	cartReminderHandler := handlers.NewCartReminderHandler(cartReminderService)
 
 	//Middleware
 	authMiddleware := middlewares.Auth(authService)
+	optionalAuthMiddleware := middlewares.OptionalAuth(authService)
 
 	// Роутер
-	router := server.NewRouter(cfg.ApiKey, productHandler, cartHandler, authHandler, authMiddleware, orderHandler, paymentHandler)
+	router := server.NewRouter(cfg.ApiKey, cfg.Storage, productHandler, cartHandler, authHandler, authMiddleware, optionalAuthMiddleware, orderHandler, paymentHandler, categoryHandler, variantHandler, imageHandler, productCSVHandler, feedHandler, exchangeHandler, reviewHandler, attributeHandler, priceHandler, recommendationHandler, wishlistHandler, cartReminderHandler)
+
+	// Фоновые задачи
+	workersCtx, stopWorkers := context.WithCancel(ctx)
+	defer stopWorkers()
+	go priceService.RunScheduler(workersCtx, time.Minute)
+	go recommendationService.RunRefresher(workersCtx, time.Hour)
+	go cartReminderService.RunReminders(workersCtx, 15*time.Minute)
 
 	// Сервер
 	srv := &http.Server{
@@ -88,6 +162,8 @@ func main() {
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 	<-sigs
 
+	stopWorkers()
+
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	if err := srv.Shutdown(shutdownCtx); err != nil {
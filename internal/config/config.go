@@ -1,9 +1,12 @@
 package config
 
 import (
+	"ecommerce-api/internal/models"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -26,13 +29,77 @@ type ApiKeyConfig struct {
 	Admin string
 }
 
+type StorageConfig struct {
+	Dir         string
+	BaseURL     string
+	ServeStatic bool
+}
+
+type FeedConfig struct {
+	ShopName           string
+	Company            string
+	ShopURL            string
+	ProductURLTemplate string
+	CacheDir           string
+}
+
+type ExchangeConfig struct {
+	Username string
+	Password string
+	Dir      string
+}
+
+// Хранилища корзин: auto — Redis с переключением на Postgres при сбое.
+const (
+	CartStorageAuto     = "auto"
+	CartStorageRedis    = "redis"
+	CartStoragePostgres = "postgres"
+)
+
+type CartConfig struct {
+	// TokenSecret подписывает токены гостевых корзин.
+	TokenSecret   string
+	MergeStrategy string
+	Storage       string
+	// ShareTTL — сколько живёт ссылка на копию корзины.
+	ShareTTL time.Duration
+}
+
+// SMTPConfig — почтовый сервер для уведомлений; без Host письма только пишутся в лог.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+type CartReminderConfig struct {
+	// IdleAfter — сколько корзина должна пролежать без изменений, чтобы о ней напомнить.
+	IdleAfter time.Duration
+	// MinInterval — не чаще одного напоминания пользователю за этот срок.
+	MinInterval time.Duration
+	// BatchSize ограничивает число писем за один проход фоновой задачи.
+	BatchSize      int
+	CartURL        string
+	UnsubscribeURL string
+	// TokenSecret подписывает ссылки для отписки из писем.
+	TokenSecret string
+}
+
 type Config struct {
-	ServerPort  string
-	DatabaseURL string
-	RedisURL    string
-	JWT         JWTConfig
-	YooKassa    YooKassaConfig
-	ApiKey      ApiKeyConfig
+	ServerPort   string
+	DatabaseURL  string
+	RedisURL     string
+	JWT          JWTConfig
+	YooKassa     YooKassaConfig
+	ApiKey       ApiKeyConfig
+	Storage      StorageConfig
+	Feed         FeedConfig
+	Exchange     ExchangeConfig
+	Cart         CartConfig
+	SMTP         SMTPConfig
+	CartReminder CartReminderConfig
 }
 
 func LoadConfig() (*Config, error) {
@@ -95,6 +162,130 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("ADMIN_API_KEY is rquired for admin endpoints")
 	}
 
+	storageDir := os.Getenv("STORAGE_DIR")
+	if storageDir == "" {
+		storageDir = "./uploads"
+	}
+
+	storageBaseURL := os.Getenv("STORAGE_BASE_URL")
+	if storageBaseURL == "" {
+		storageBaseURL = "/static"
+	}
+
+	serveStatic := true
+	if v := os.Getenv("SERVE_STATIC"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			serveStatic = b
+		}
+	}
+
+	shopURL := strings.TrimRight(os.Getenv("SHOP_URL"), "/")
+	if shopURL == "" {
+		shopURL = "http://localhost" + serverPort
+	}
+
+	shopName := os.Getenv("SHOP_NAME")
+	if shopName == "" {
+		shopName = "Shop"
+	}
+
+	company := os.Getenv("SHOP_COMPANY")
+	if company == "" {
+		company = shopName
+	}
+
+	productURLTemplate := os.Getenv("PRODUCT_URL_TEMPLATE")
+	if productURLTemplate == "" {
+		productURLTemplate = shopURL + "/products/{id}"
+	}
+
+	feedCacheDir := os.Getenv("FEED_CACHE_DIR")
+	if feedCacheDir == "" {
+		feedCacheDir = filepath.Join(os.TempDir(), "ecommerce-feeds")
+	}
+
+	exchangeDir := os.Getenv("EXCHANGE_1C_DIR")
+	if exchangeDir == "" {
+		exchangeDir = filepath.Join(os.TempDir(), "ecommerce-1c-exchange")
+	}
+
+	cartTokenSecret := os.Getenv("CART_TOKEN_SECRET")
+	if cartTokenSecret == "" {
+		cartTokenSecret = jwtSecret
+	}
+
+	cartMergeStrategy := os.Getenv("CART_MERGE_STRATEGY")
+	switch cartMergeStrategy {
+	case "":
+		cartMergeStrategy = models.CartMergeSum
+	case models.CartMergeSum, models.CartMergeMax, models.CartMergePreferUser:
+	default:
+		return nil, fmt.Errorf("CART_MERGE_STRATEGY must be one of sum, max, user")
+	}
+
+	cartStorage := os.Getenv("CART_STORAGE")
+	switch cartStorage {
+	case "":
+		cartStorage = CartStorageAuto
+	case CartStorageAuto, CartStorageRedis, CartStoragePostgres:
+	default:
+		return nil, fmt.Errorf("CART_STORAGE must be one of auto, redis, postgres")
+	}
+
+	cartShareTTL := 72 * time.Hour
+	if h := os.Getenv("CART_SHARE_TTL_HOURS"); h != "" {
+		if hours, err := strconv.Atoi(h); err == nil && hours > 0 {
+			cartShareTTL = time.Duration(hours) * time.Hour
+		}
+	}
+
+	smtpPort := 587
+	if v := os.Getenv("SMTP_PORT"); v != "" {
+		if port, err := strconv.Atoi(v); err == nil && port > 0 {
+			smtpPort = port
+		}
+	}
+
+	smtpFrom := os.Getenv("SMTP_FROM")
+	if smtpFrom == "" {
+		smtpFrom = os.Getenv("SMTP_USERNAME")
+	}
+
+	// Напоминать имеет смысл, пока корзина ещё не истекла (7 дней).
+	reminderIdle := 24 * time.Hour
+	if h := os.Getenv("CART_REMINDER_IDLE_HOURS"); h != "" {
+		if hours, err := strconv.Atoi(h); err == nil && hours > 0 {
+			reminderIdle = time.Duration(hours) * time.Hour
+		}
+	}
+	if reminderIdle >= 7*24*time.Hour {
+		return nil, fmt.Errorf("CART_REMINDER_IDLE_HOURS must be less than the cart lifetime of 168 hours")
+	}
+
+	reminderInterval := 72 * time.Hour
+	if h := os.Getenv("CART_REMINDER_MIN_INTERVAL_HOURS"); h != "" {
+		if hours, err := strconv.Atoi(h); err == nil && hours > 0 {
+			reminderInterval = time.Duration(hours) * time.Hour
+		}
+	}
+
+	reminderBatch := 100
+	if v := os.Getenv("CART_REMINDER_BATCH_SIZE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			reminderBatch = n
+		}
+	}
+
+	cartURL := os.Getenv("CART_URL")
+	if cartURL == "" {
+		cartURL = shopURL + "/cart"
+	}
+
+	unsubscribeURL := os.Getenv("CART_REMINDER_UNSUBSCRIBE_URL")
+	if unsubscribeURL == "" {
+		unsubscribeURL = shopURL + "/cart/reminders/unsubscribe"
+	}
+
 	return &Config{
 		ServerPort:  serverPort,
 		DatabaseURL: databaseURL,
@@ -113,5 +304,43 @@ func LoadConfig() (*Config, error) {
 		ApiKey: ApiKeyConfig{
 			Admin: adminApiKey,
 		},
+		Storage: StorageConfig{
+			Dir:         storageDir,
+			BaseURL:     storageBaseURL,
+			ServeStatic: serveStatic,
+		},
+		Feed: FeedConfig{
+			ShopName:           shopName,
+			Company:            company,
+			ShopURL:            shopURL,
+			ProductURLTemplate: productURLTemplate,
+			CacheDir:           feedCacheDir,
+		},
+		Exchange: ExchangeConfig{
+			Username: os.Getenv("EXCHANGE_1C_USER"),
+			Password: os.Getenv("EXCHANGE_1C_PASSWORD"),
+			Dir:      exchangeDir,
+		},
+		Cart: CartConfig{
+			TokenSecret:   cartTokenSecret,
+			MergeStrategy: cartMergeStrategy,
+			Storage:       cartStorage,
+			ShareTTL:      cartShareTTL,
+		},
+		SMTP: SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     smtpPort,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     smtpFrom,
+		},
+		CartReminder: CartReminderConfig{
+			IdleAfter:      reminderIdle,
+			MinInterval:    reminderInterval,
+			BatchSize:      reminderBatch,
+			CartURL:        cartURL,
+			UnsubscribeURL: unsubscribeURL,
+			TokenSecret:    cartTokenSecret,
+		},
 	}, nil
 }
@@ -3,15 +3,21 @@ package db
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
 func NewRedisClient(ctx context.Context, addr string) (*redis.Client, error) {
+	// Короткие таймауты: при падении Redis запросы должны быстро уходить
+	// в Postgres, а не ждать стандартные 5 секунд на подключение.
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     addr,
-		Password: "",
-		DB:       0,
+		Addr:         addr,
+		Password:     "",
+		DB:           0,
+		DialTimeout:  time.Second,
+		ReadTimeout:  500 * time.Millisecond,
+		WriteTimeout: 500 * time.Millisecond,
 	})
 
 	if err := rdb.Ping(ctx).Err(); err != nil {
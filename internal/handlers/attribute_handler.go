@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"ecommerce-api/internal/models"
+	"ecommerce-api/internal/repositories"
+	"ecommerce-api/internal/services"
+	"errors"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// attributeFilterKey разбирает ключи вида attr[brand] и attr[ram_gb][gte].
+var attributeFilterKey = regexp.MustCompile(`^attr\[([^\[\]]+)\](?:\[(eq|gt|gte|lt|lte)\])?$`)
+
+type AttributeHandler struct {
+	service services.AttributeService
+}
+
+func NewAttributeHandler(service services.AttributeService) *AttributeHandler {
+	return &AttributeHandler{service: service}
+}
+
+func (h *AttributeHandler) Create(c *gin.Context) {
+	var req models.CreateAttributeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	attr, err := h.service.CreateAttribute(c.Request.Context(), &req)
+	if err != nil {
+		respondAttributeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, attr)
+}
+
+func (h *AttributeHandler) List(c *gin.Context) {
+	attributes, err := h.service.ListAttributes(c.Request.Context())
+	if err != nil {
+		respondAttributeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, attributes)
+}
+
+func (h *AttributeHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid attribute id"})
+		return
+	}
+
+	if err := h.service.DeleteAttribute(c.Request.Context(), id); err != nil {
+		respondAttributeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
+
+func (h *AttributeHandler) SetProductAttributes(c *gin.Context) {
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+		return
+	}
+
+	var req models.SetProductAttributesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.SetProductAttributes(c.Request.Context(), productID, &req); err != nil {
+		respondAttributeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "attributes updated"})
+}
+
+// Facets принимает те же фильтры, что и GET /products.
+func (h *AttributeHandler) Facets(c *gin.Context) {
+	var params models.ProductListParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	params.Attributes = parseAttributeFilters(c)
+
+	response, err := h.service.Facets(c.Request.Context(), &params)
+	if err != nil {
+		respondAttributeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// parseAttributeFilters собирает фильтры attr[...] из query-строки.
+// Повторяющиеся значения одного атрибута означают «любое из».
+func parseAttributeFilters(c *gin.Context) []models.AttributeFilter {
+	query := c.Request.URL.Query()
+
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var filters []models.AttributeFilter
+	for _, key := range keys {
+		m := attributeFilterKey.FindStringSubmatch(key)
+		if m == nil {
+			continue
+		}
+
+		op := m[2]
+		if op == "" {
+			op = "eq"
+		}
+
+		filters = append(filters, models.AttributeFilter{
+			Code:   m[1],
+			Op:     op,
+			Values: query[key],
+		})
+	}
+
+	return filters
+}
+
+func respondAttributeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, services.ErrInvalidAttribute), errors.Is(err, services.ErrInvalidAttributeFilter),
+		errors.Is(err, repositories.ErrInvalidCursor):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, repositories.ErrAttributeCodeTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -3,17 +3,23 @@ package handlers
 import (
 	"ecommerce-api/internal/models"
 	"ecommerce-api/internal/services"
+	"errors"
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
 type AuthHandler struct {
-	service services.AuthService
+	service     services.AuthService
+	cartService services.CartService
 }
 
-func NewAuthHandler(service services.AuthService) *AuthHandler {
-	return &AuthHandler{service: service}
+func NewAuthHandler(service services.AuthService, cartService services.CartService) *AuthHandler {
+	return &AuthHandler{
+		service:     service,
+		cartService: cartService,
+	}
 }
 
 func (h *AuthHandler) Register(c *gin.Context) {
@@ -29,6 +35,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		c.JSON(http.StatusConflict, gin.H{"error": "email already exists"})
 		return
 	}
+	h.mergeGuestCart(c, id)
 
 	c.JSON(http.StatusCreated, gin.H{"id": id, "message": "user registered successfully"})
 }
@@ -40,11 +47,28 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := h.service.Login(c.Request.Context(), &req)
+	token, userID, err := h.service.Login(c.Request.Context(), &req)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
 	}
+	h.mergeGuestCart(c, userID)
 
 	c.JSON(http.StatusOK, models.LoginResponse{Token: token})
 }
+
+// mergeGuestCart переносит гостевую корзину из запроса пользователю.
+// Ошибка слияния не должна мешать входу, поэтому она только логируется.
+func (h *AuthHandler) mergeGuestCart(c *gin.Context, userID int64) {
+	token := guestCartToken(c)
+	if token == "" {
+		return
+	}
+
+	err := h.cartService.MergeGuestCart(c.Request.Context(), token, userID)
+	if err != nil && !errors.Is(err, services.ErrInvalidCartToken) {
+		log.Printf("merge guest cart for user %d: %v", userID, err)
+		return
+	}
+	clearCartToken(c)
+}
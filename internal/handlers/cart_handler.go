@@ -1,12 +1,23 @@
 package handlers
 
 import (
+	"ecommerce-api/internal/models"
+	"ecommerce-api/internal/repositories"
 	"ecommerce-api/internal/services"
+	"errors"
+	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	cartCookieName = "cart_token"
+	// cartTokenHeader — для мобильных клиентов, которые не хранят cookie.
+	cartTokenHeader  = "X-Cart-Token"
+	cartCookieMaxAge = 7 * 24 * 60 * 60
+)
+
 type CartHandler struct {
 	service services.CartService
 }
@@ -16,14 +27,9 @@ func NewCartHandler(cartService services.CartService) *CartHandler {
 }
 
 func (ch *CartHandler) AddToCart(c *gin.Context) {
-	userID := getUserID(c)
-	if userID == 0 {
-		c.JSON(401, gin.H{"error": "unauthorized"})
-		return
-	}
-
 	var req struct {
 		ProductID int64 `json:"product_id" binding:"required"`
+		VariantID int64 `json:"variant_id" binding:"gte=0"`
 		Quantity  int   `json:"quantity" binding:"required,gt=0"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -31,26 +37,27 @@ func (ch *CartHandler) AddToCart(c *gin.Context) {
 		return
 	}
 
-	ctx := c.Request.Context()
-	if err := ch.service.AddItem(ctx, userID, req.ProductID, req.Quantity); err != nil {
+	owner, _, err := ch.cartOwner(c, true)
+	if err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(200, gin.H{"message": "item added to cart"})
-}
-
-func (ch *CartHandler) UpdateCartItem(c *gin.Context) {
-	userID := getUserID(c)
-	if userID == 0 {
-		c.JSON(401, gin.H{"error": "unauthorized"})
+	ctx := c.Request.Context()
+	item := models.CartItemKey{ProductID: req.ProductID, VariantID: req.VariantID}
+	quantity, capped, err := ch.service.AddItem(ctx, owner, item, req.Quantity)
+	if err != nil {
+		respondCartError(c, err)
 		return
 	}
 
-	productIDStr := c.Param("product_id")
-	productID, err := strconv.ParseInt(productIDStr, 10, 64)
+	c.JSON(200, gin.H{"message": "item added to cart", "quantity": quantity, "capped": capped})
+}
+
+func (ch *CartHandler) UpdateCartItem(c *gin.Context) {
+	item, err := parseCartItemKey(c)
 	if err != nil {
-		c.JSON(400, gin.H{"error": "invalid product_id"})
+		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -62,47 +69,61 @@ func (ch *CartHandler) UpdateCartItem(c *gin.Context) {
 		return
 	}
 
-	ctx := c.Request.Context()
-	if err := ch.service.UpdateItem(ctx, userID, productID, req.Quantity); err != nil {
+	owner, _, err := ch.cartOwner(c, true)
+	if err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(200, gin.H{"message": "cart item updated"})
+	ctx := c.Request.Context()
+	quantity, capped, err := ch.service.UpdateItem(ctx, owner, item, req.Quantity)
+	if err != nil {
+		respondCartError(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "cart item updated", "quantity": quantity, "capped": capped})
 }
 
 func (ch *CartHandler) RemoveFromCart(c *gin.Context) {
-	userID := getUserID(c)
-	if userID == 0 {
-		c.JSON(401, gin.H{"error": "unauthorized"})
+	item, err := parseCartItemKey(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
 
-	productIDStr := c.Param("product_id")
-	productID, err := strconv.ParseInt(productIDStr, 10, 64)
+	owner, found, err := ch.cartOwner(c, false)
 	if err != nil {
-		c.JSON(400, gin.H{"error": "invalid product_id"})
+		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
 
 	ctx := c.Request.Context()
-	if err := ch.service.RemoveItem(ctx, userID, productID); err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
-		return
+	if found {
+		if err := ch.service.RemoveItem(ctx, owner, item); err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
 	}
 
 	c.JSON(200, gin.H{"message": "item removed from cart"})
 }
 
 func (ch *CartHandler) GetCart(c *gin.Context) {
-	userID := getUserID(c)
-	if userID == 0 {
-		c.JSON(401, gin.H{"error": "unauthorized"})
+	owner, found, err := ch.cartOwner(c, false)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
+	if !found {
+		c.JSON(200, &models.CartResponse{Items: []models.CartResponseItem{}, Warnings: []models.CartWarning{}})
+		return
+	}
+
+	recommendations, _ := strconv.Atoi(c.Query("recommendations"))
 
 	ctx := c.Request.Context()
-	response, err := ch.service.GetCartResponse(ctx, userID)
+	response, err := ch.service.GetCartResponse(ctx, owner, recommendations)
 	if err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
@@ -112,19 +133,139 @@ func (ch *CartHandler) GetCart(c *gin.Context) {
 }
 
 func (ch *CartHandler) ClearCart(c *gin.Context) {
-	userID := getUserID(c)
-	if userID == 0 {
-		c.JSON(401, gin.H{"error": "unauthorized"})
+	owner, found, err := ch.cartOwner(c, false)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
 
 	ctx := c.Request.Context()
-	if err := ch.service.ClearCart(ctx, userID); err != nil {
+	if found {
+		if err := ch.service.ClearCart(ctx, owner); err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(200, gin.H{"message": "cart cleared"})
+}
+
+func (ch *CartHandler) ShareCart(c *gin.Context) {
+	owner, found, err := ch.cartOwner(c, false)
+	if err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
+	if !found {
+		respondCartError(c, services.ErrCartEmpty)
+		return
+	}
 
-	c.JSON(200, gin.H{"message": "cart cleared"})
+	response, err := ch.service.ShareCart(c.Request.Context(), owner)
+	if err != nil {
+		respondCartError(c, err)
+		return
+	}
+
+	c.JSON(201, response)
+}
+
+func (ch *CartHandler) ImportCart(c *gin.Context) {
+	owner, _, err := ch.cartOwner(c, true)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := ch.service.ImportCart(c.Request.Context(), owner, c.Param("token"))
+	if err != nil {
+		respondCartError(c, err)
+		return
+	}
+
+	c.JSON(200, response)
+}
+
+func respondCartError(c *gin.Context, err error) {
+	var ruleErr *services.PurchaseRuleError
+	switch {
+	case errors.As(err, &ruleErr):
+		c.JSON(422, ruleErr)
+	case errors.Is(err, services.ErrProductNotFound), errors.Is(err, services.ErrVariantNotFound):
+		c.JSON(404, gin.H{"error": err.Error()})
+	case errors.Is(err, repositories.ErrCartShareNotFound):
+		c.JSON(404, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrVariantRequired), errors.Is(err, services.ErrCartEmpty):
+		c.JSON(400, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrOutOfStock):
+		c.JSON(409, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCartSharingUnavailable):
+		c.JSON(503, gin.H{"error": err.Error()})
+	default:
+		c.JSON(500, gin.H{"error": err.Error()})
+	}
+}
+
+// cartOwner определяет, чья корзина: вошедшего пользователя или гостя
+// по токену корзины. found = false, если у гостя ещё нет корзины; при
+// create гостю без действующего токена выдаётся новый.
+func (ch *CartHandler) cartOwner(c *gin.Context, create bool) (owner models.CartOwner, found bool, err error) {
+	if userID := getUserID(c); userID != 0 {
+		return models.UserCart(userID), true, nil
+	}
+
+	if token := guestCartToken(c); token != "" {
+		if guestID, err := ch.service.ParseGuestToken(token); err == nil {
+			return models.GuestCart(guestID), true, nil
+		}
+	}
+
+	if !create {
+		return models.CartOwner{}, false, nil
+	}
+
+	token, guestID, err := ch.service.IssueGuestToken()
+	if err != nil {
+		return models.CartOwner{}, false, err
+	}
+	setCartToken(c, token)
+	return models.GuestCart(guestID), true, nil
+}
+
+func guestCartToken(c *gin.Context) string {
+	if token := c.GetHeader(cartTokenHeader); token != "" {
+		return token
+	}
+	token, _ := c.Cookie(cartCookieName)
+	return token
+}
+
+func setCartToken(c *gin.Context, token string) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(cartCookieName, token, cartCookieMaxAge, "/", "", c.Request.TLS != nil, true)
+	c.Header(cartTokenHeader, token)
+}
+
+func clearCartToken(c *gin.Context) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(cartCookieName, "", -1, "/", "", c.Request.TLS != nil, true)
+}
+
+// parseCartItemKey читает товар из пути и необязательный вариант из ?variant_id=.
+func parseCartItemKey(c *gin.Context) (models.CartItemKey, error) {
+	productID, err := strconv.ParseInt(c.Param("product_id"), 10, 64)
+	if err != nil {
+		return models.CartItemKey{}, errors.New("invalid product_id")
+	}
+
+	item := models.CartItemKey{ProductID: productID}
+	if v := c.Query("variant_id"); v != "" {
+		item.VariantID, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || item.VariantID < 0 {
+			return models.CartItemKey{}, errors.New("invalid variant_id")
+		}
+	}
+	return item, nil
 }
 
 func getUserID(c *gin.Context) int64 {
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"ecommerce-api/internal/models"
+	"ecommerce-api/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+type CartReminderHandler struct {
+	service services.CartReminderService
+}
+
+func NewCartReminderHandler(service services.CartReminderService) *CartReminderHandler {
+	return &CartReminderHandler{service: service}
+}
+
+func (h *CartReminderHandler) SetEnabled(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req models.CartRemindersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.SetRemindersEnabled(c.Request.Context(), userID, *req.Enabled); err != nil {
+		respondCartReminderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "cart reminder settings updated", "enabled": *req.Enabled})
+}
+
+// Unsubscribe — ссылка из письма, поэтому GET и без авторизации.
+func (h *CartReminderHandler) Unsubscribe(c *gin.Context) {
+	if err := h.service.Unsubscribe(c.Request.Context(), c.Query("token")); err != nil {
+		respondCartReminderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "unsubscribed from cart reminders"})
+}
+
+func respondCartReminderError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidUnsubscribeToken):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, pgx.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
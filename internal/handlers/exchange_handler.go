@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"ecommerce-api/internal/services"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const exchangeCookieName = "ecommerce_1c"
+
+type ExchangeHandler struct {
+	service services.ExchangeService
+}
+
+func NewExchangeHandler(service services.ExchangeService) *ExchangeHandler {
+	return &ExchangeHandler{service: service}
+}
+
+// Handle обслуживает /1c_exchange. 1С ждёт ответы простым текстом:
+// первая строка — success/failure, дальше данные режима.
+func (h *ExchangeHandler) Handle(c *gin.Context) {
+	exchangeType := c.Query("type")
+	mode := c.Query("mode")
+
+	if exchangeType != "catalog" && exchangeType != "sale" {
+		h.failure(c, http.StatusBadRequest, "unknown type")
+		return
+	}
+
+	if mode == "checkauth" {
+		h.checkAuth(c)
+		return
+	}
+
+	sessionID, _ := c.Cookie(exchangeCookieName)
+	if err := h.service.ValidateSession(sessionID); err != nil {
+		h.failure(c, http.StatusUnauthorized, "not authorized")
+		return
+	}
+
+	switch mode {
+	case "init":
+		c.String(http.StatusOK, "zip=yes\nfile_limit=%d", h.service.FileLimit())
+
+	case "file":
+		err := h.service.SaveFile(sessionID, c.Query("filename"), c.Request.Body)
+		h.respond(c, err)
+
+	case "import":
+		if exchangeType != "catalog" {
+			h.failure(c, http.StatusBadRequest, "import is supported for catalog only")
+			return
+		}
+		err := h.service.ImportCatalog(c.Request.Context(), sessionID, c.Query("filename"))
+		h.respond(c, err)
+
+	case "query":
+		if exchangeType != "sale" {
+			h.failure(c, http.StatusBadRequest, "query is supported for sale only")
+			return
+		}
+		c.Header("Content-Type", "application/xml; charset=utf-8")
+		c.Status(http.StatusOK)
+		// Заголовки уже отправлены, поэтому ошибку посреди выгрузки можно только залогировать.
+		if err := h.service.WriteOrders(c.Request.Context(), sessionID, c.Writer); err != nil {
+			log.Printf("1c orders export failed: %v", err)
+		}
+
+	case "success":
+		err := h.service.ConfirmOrders(c.Request.Context(), sessionID)
+		h.respond(c, err)
+
+	default:
+		h.failure(c, http.StatusBadRequest, "unknown mode")
+	}
+}
+
+func (h *ExchangeHandler) checkAuth(c *gin.Context) {
+	username, password, _ := c.Request.BasicAuth()
+
+	sessionID, err := h.service.CheckAuth(username, password)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrExchangeDisabled):
+			h.failure(c, http.StatusNotFound, err.Error())
+		case errors.Is(err, services.ErrExchangeUnauthorized):
+			h.failure(c, http.StatusUnauthorized, "invalid credentials")
+		default:
+			log.Printf("1c checkauth error: %v", err)
+			h.failure(c, http.StatusInternalServerError, "internal error")
+		}
+		return
+	}
+
+	c.String(http.StatusOK, "success\n%s\n%s", exchangeCookieName, sessionID)
+}
+
+func (h *ExchangeHandler) respond(c *gin.Context, err error) {
+	switch {
+	case err == nil:
+		c.String(http.StatusOK, "success")
+	case errors.Is(err, services.ErrExchangeUnauthorized):
+		h.failure(c, http.StatusUnauthorized, "not authorized")
+	case errors.Is(err, services.ErrInvalidExchangeFile):
+		h.failure(c, http.StatusBadRequest, err.Error())
+	default:
+		log.Printf("1c exchange error: %v", err)
+		h.failure(c, http.StatusInternalServerError, "internal error")
+	}
+}
+
+func (h *ExchangeHandler) failure(c *gin.Context, status int, message string) {
+	c.String(status, "failure\n%s", message)
+}
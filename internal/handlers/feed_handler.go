@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"ecommerce-api/internal/services"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FeedHandler struct {
+	service services.FeedService
+}
+
+func NewFeedHandler(service services.FeedService) *FeedHandler {
+	return &FeedHandler{service: service}
+}
+
+func (h *FeedHandler) YandexMarket(c *gin.Context) {
+	path, err := h.service.YandexFeedPath(c.Request.Context())
+	if err != nil {
+		log.Printf("yandex feed error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "feed is unavailable"})
+		return
+	}
+
+	c.Header("Content-Type", "application/xml; charset=utf-8")
+	c.File(path)
+}
@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"ecommerce-api/internal/models"
 	"ecommerce-api/internal/services"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5"
@@ -24,9 +27,27 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		return
 	}
 
+	// Тело необязательно: без изменившихся цен заказ оформляется и без checksum.
+	var req models.CreateOrderRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	ctx := c.Request.Context()
-	resp, err := h.service.CreateOrder(ctx, userID)
+	resp, err := h.service.CreateOrder(ctx, userID, &req)
 	if err != nil {
+		if errors.Is(err, services.ErrCartChanged) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		var ruleErr *services.PurchaseRuleError
+		if errors.As(err, &ruleErr) {
+			c.JSON(http.StatusUnprocessableEntity, ruleErr)
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -48,6 +69,15 @@ func (h *OrderHandler) ListOrders(c *gin.Context) {
 		return
 	}
 
+	// Заказы не удаляются, поэтому список меняется только вместе
+	// с updated_at какого-то из заказов.
+	var lastModified time.Time
+	for _, o := range orders {
+		if o.UpdatedAt.After(lastModified) {
+			lastModified = o.UpdatedAt
+		}
+	}
+	setLastModified(c, lastModified)
 	c.JSON(http.StatusOK, orders)
 }
 
@@ -76,9 +106,69 @@ func (h *OrderHandler) GetOrder(c *gin.Context) {
 		return
 	}
 
+	setLastModified(c, order.UpdatedAt)
 	c.JSON(http.StatusOK, order)
 }
 
+func (h *OrderHandler) GetOrderHistory(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id"})
+		return
+	}
+
+	history, err := h.service.GetOrderHistory(c.Request.Context(), orderID, userID)
+	if err != nil {
+		respondOrderStatusError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// UpdateStatus — ручная смена статуса администратором (сборка, отгрузка, возврат).
+func (h *OrderHandler) UpdateStatus(c *gin.Context) {
+	orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id"})
+		return
+	}
+
+	var req models.UpdateOrderStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	change := models.OrderStatusChange{
+		Source:  models.OrderSourceAdmin,
+		Comment: req.Comment,
+	}
+	if err := h.service.UpdateOrderStatus(c.Request.Context(), orderID, req.Status, change); err != nil {
+		respondOrderStatusError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "order status updated", "status": req.Status})
+}
+
+func respondOrderStatusError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+	case errors.Is(err, services.ErrInvalidOrderTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
 func (h *OrderHandler) PaymentSuccess(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Оплата прошла успешно. Заказ в обработке."})
 }
@@ -2,12 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"net"
 	"net/http"
 	"strconv"
 
+	"ecommerce-api/internal/models"
 	"ecommerce-api/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -31,6 +33,8 @@ var yookassaAllowedCIDRs = []string{
 	"2a02:5180::/32",
 }
 
+var webhookStatusChange = models.OrderStatusChange{Source: models.OrderSourcePaymentWebhook}
+
 func (h *PaymentHandler) HandleWebhook(c *gin.Context) {
 	clientIP := c.ClientIP()
 	if !isIPAllowed(clientIP, yookassaAllowedCIDRs) {
@@ -76,21 +80,30 @@ func (h *PaymentHandler) HandleWebhook(c *gin.Context) {
 			}
 
 			ctx := c.Request.Context()
-			if err := h.orderService.UpdateOrderStatus(ctx, orderID, "paid"); err != nil {
+			err = h.orderService.UpdateOrderStatus(ctx, orderID, models.OrderPaid, webhookStatusChange)
+			switch {
+			case errors.Is(err, services.ErrInvalidOrderTransition):
+				// Повтор вебхука не поможет: например, заказ уже отменён,
+				// и деньги нужно вернуть вручную.
+				log.Printf("Webhook: payment for order %d succeeded but %v, needs manual review", orderID, err)
+			case err != nil:
 				log.Printf("Webhook: failed to update order %d: %v", orderID, err)
 				c.Status(http.StatusInternalServerError)
 				return
+			default:
+				log.Printf("Webhook: order %d updated to paid", orderID)
 			}
 
-			log.Printf("Webhook: order %d updated to paid", orderID)
-
 		case "payment.canceled":
 			orderIDStr := payload.Object.Metadata.OrderID
 			orderID, _ := strconv.ParseInt(orderIDStr, 10, 64)
 			if orderID != 0 {
 				ctx := c.Request.Context()
-				_ = h.orderService.UpdateOrderStatus(ctx, orderID, "canceled")
-				log.Printf("Webhook: order %d canceled", orderID)
+				if err := h.orderService.UpdateOrderStatus(ctx, orderID, models.OrderCanceled, webhookStatusChange); err != nil {
+					log.Printf("Webhook: order %d not canceled: %v", orderID, err)
+				} else {
+					log.Printf("Webhook: order %d canceled", orderID)
+				}
 			}
 
 		default:
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"ecommerce-api/internal/models"
+	"ecommerce-api/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+type PriceHandler struct {
+	service services.PriceService
+}
+
+func NewPriceHandler(service services.PriceService) *PriceHandler {
+	return &PriceHandler{service: service}
+}
+
+func (h *PriceHandler) History(c *gin.Context) {
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+		return
+	}
+
+	var params models.PriceHistoryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	history, err := h.service.GetHistory(c.Request.Context(), productID, &params)
+	if err != nil {
+		respondPriceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+func (h *PriceHandler) Schedule(c *gin.Context) {
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+		return
+	}
+
+	var req models.SchedulePriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scheduled, err := h.service.SchedulePrice(c.Request.Context(), productID, &req)
+	if err != nil {
+		respondPriceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, scheduled)
+}
+
+func (h *PriceHandler) ListScheduled(c *gin.Context) {
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+		return
+	}
+
+	scheduled, err := h.service.ListScheduled(c.Request.Context(), productID)
+	if err != nil {
+		respondPriceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, scheduled)
+}
+
+func (h *PriceHandler) CancelScheduled(c *gin.Context) {
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+		return
+	}
+
+	scheduleID, err := strconv.ParseInt(c.Param("schedule_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid schedule_id"})
+		return
+	}
+
+	if err := h.service.CancelScheduled(c.Request.Context(), productID, scheduleID); err != nil {
+		respondPriceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "canceled"})
+}
+
+func respondPriceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, services.ErrApplyAtInPast):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"ecommerce-api/internal/services"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProductCSVHandler struct {
+	service services.ProductCSVService
+}
+
+func NewProductCSVHandler(service services.ProductCSVService) *ProductCSVHandler {
+	return &ProductCSVHandler{service: service}
+}
+
+// Import принимает CSV либо как multipart-поле file, либо как тело запроса
+// с Content-Type text/csv.
+func (h *ProductCSVHandler) Import(c *gin.Context) {
+	delimiter, ok := csvDelimiter(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "delimiter must be ',' or ';'"})
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+
+	var body io.Reader = c.Request.Body
+	if fileHeader, err := c.FormFile("file"); err == nil {
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	report, err := h.service.Import(c.Request.Context(), body, delimiter, dryRun)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCSV) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *ProductCSVHandler) Export(c *gin.Context) {
+	delimiter, ok := csvDelimiter(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "delimiter must be ',' or ';'"})
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="products.csv"`)
+	c.Status(http.StatusOK)
+
+	// Заголовки уже отправлены, поэтому ошибку посреди выгрузки можно только залогировать.
+	if err := h.service.Export(c.Request.Context(), c.Writer, delimiter); err != nil {
+		log.Printf("products export failed: %v", err)
+	}
+}
+
+func csvDelimiter(c *gin.Context) (rune, bool) {
+	switch c.DefaultQuery("delimiter", ",") {
+	case ",":
+		return ',', true
+	case ";":
+		return ';', true
+	default:
+		return 0, false
+	}
+}
@@ -2,9 +2,15 @@ package handlers
 
 import (
 	"ecommerce-api/internal/models"
+	"ecommerce-api/internal/repositories"
 	"ecommerce-api/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
 )
 
 type ProductHandler struct {
@@ -33,12 +39,168 @@ func (ph *ProductHandler) Create(c *gin.Context) {
 }
 
 func (ph *ProductHandler) List(c *gin.Context) {
+	var params models.ProductListParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if params.MinPrice != nil && params.MaxPrice != nil && *params.MinPrice > *params.MaxPrice {
+		c.JSON(400, gin.H{"error": "min_price must not exceed max_price"})
+		return
+	}
+
+	params.Attributes = parseAttributeFilters(c)
+
 	ctx := c.Request.Context()
-	products, err := ph.service.GetProducts(ctx)
+	products, err := ph.service.GetProducts(ctx, &params)
 	if err != nil {
+		if errors.Is(err, repositories.ErrInvalidCursor) || errors.Is(err, services.ErrInvalidAttributeFilter) {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(200, products)
 }
+
+func (ph *ProductHandler) Get(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid product id"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	product, err := ph.service.GetProduct(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(404, gin.H{"error": "product not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	setLastModified(c, product.UpdatedAt)
+	c.JSON(200, product)
+}
+
+func (ph *ProductHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid product id"})
+		return
+	}
+
+	var req models.UpdateProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	product, err := ph.service.UpdateProduct(ctx, id, &req)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(404, gin.H{"error": "product not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, product)
+}
+
+func (ph *ProductHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid product id"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := ph.service.DeleteProduct(ctx, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(404, gin.H{"error": "product not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "deleted"})
+}
+
+func (ph *ProductHandler) SetPurchaseRules(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid product id"})
+		return
+	}
+
+	var req models.SetPurchaseRulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	rules, err := ph.service.SetPurchaseRules(c.Request.Context(), id, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			c.JSON(404, gin.H{"error": "product not found"})
+		case errors.Is(err, services.ErrInvalidPurchaseRules):
+			c.JSON(400, gin.H{"error": err.Error()})
+		default:
+			c.JSON(500, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(200, rules)
+}
+
+func (ph *ProductHandler) DeletePurchaseRules(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid product id"})
+		return
+	}
+
+	if err := ph.service.DeletePurchaseRules(c.Request.Context(), id); err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "purchase rules removed"})
+}
+
+func (ph *ProductHandler) Search(c *gin.Context) {
+	var params models.ProductSearchParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	results, err := ph.service.SearchProducts(ctx, &params)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, results)
+}
+
+// setLastModified выставляет Last-Modified для ConditionalGET. Для списка
+// товаров его не ставим: удаление товара не сдвигает максимум updated_at,
+// и If-Modified-Since вернул бы 304 на изменившуюся страницу.
+func setLastModified(c *gin.Context, t time.Time) {
+	if !t.IsZero() {
+		c.Header("Last-Modified", t.UTC().Format(http.TimeFormat))
+	}
+}
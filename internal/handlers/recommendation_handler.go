@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"ecommerce-api/internal/models"
+	"ecommerce-api/internal/repositories"
+	"ecommerce-api/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+type RecommendationHandler struct {
+	service services.RecommendationService
+}
+
+func NewRecommendationHandler(service services.RecommendationService) *RecommendationHandler {
+	return &RecommendationHandler{service: service}
+}
+
+func (h *RecommendationHandler) Related(c *gin.Context) {
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+		return
+	}
+
+	var params models.RelatedProductsParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	related, err := h.service.Related(c.Request.Context(), productID, params.Limit)
+	if err != nil {
+		respondRecommendationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, related)
+}
+
+func (h *RecommendationHandler) SetRelated(c *gin.Context) {
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+		return
+	}
+
+	var req models.SetRelatedProductsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.SetRelated(c.Request.Context(), productID, &req); err != nil {
+		respondRecommendationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "related products updated"})
+}
+
+func respondRecommendationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+	case errors.Is(err, services.ErrInvalidRelatedProduct), errors.Is(err, repositories.ErrRelatedProductNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
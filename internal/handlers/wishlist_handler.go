@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"ecommerce-api/internal/models"
+	"ecommerce-api/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+type WishlistHandler struct {
+	service services.WishlistService
+}
+
+func NewWishlistHandler(service services.WishlistService) *WishlistHandler {
+	return &WishlistHandler{service: service}
+}
+
+func (h *WishlistHandler) List(c *gin.Context) {
+	wishlists, err := h.service.List(c.Request.Context(), getUserID(c))
+	if err != nil {
+		respondWishlistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, wishlists)
+}
+
+func (h *WishlistHandler) Create(c *gin.Context) {
+	var req models.CreateWishlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	wishlist, err := h.service.Create(c.Request.Context(), getUserID(c), &req)
+	if err != nil {
+		respondWishlistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, wishlist)
+}
+
+func (h *WishlistHandler) Get(c *gin.Context) {
+	id, err := parseWishlistID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	wishlist, err := h.service.Get(c.Request.Context(), getUserID(c), id)
+	if err != nil {
+		respondWishlistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, wishlist)
+}
+
+func (h *WishlistHandler) GetShared(c *gin.Context) {
+	wishlist, err := h.service.GetShared(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		respondWishlistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, wishlist)
+}
+
+func (h *WishlistHandler) Update(c *gin.Context) {
+	id, err := parseWishlistID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req models.UpdateWishlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	wishlist, err := h.service.Update(c.Request.Context(), getUserID(c), id, &req)
+	if err != nil {
+		respondWishlistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, wishlist)
+}
+
+func (h *WishlistHandler) Delete(c *gin.Context) {
+	id, err := parseWishlistID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), getUserID(c), id); err != nil {
+		respondWishlistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "wishlist deleted"})
+}
+
+func (h *WishlistHandler) AddItem(c *gin.Context) {
+	id, err := parseWishlistID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req models.AddWishlistItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item := models.CartItemKey{ProductID: req.ProductID, VariantID: req.VariantID}
+	if err := h.service.AddItem(c.Request.Context(), getUserID(c), id, item, req.Quantity); err != nil {
+		respondWishlistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "item added to wishlist"})
+}
+
+func (h *WishlistHandler) RemoveItem(c *gin.Context) {
+	id, err := parseWishlistID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := parseCartItemKey(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.RemoveItem(c.Request.Context(), getUserID(c), id, item); err != nil {
+		respondWishlistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "item removed from wishlist"})
+}
+
+func (h *WishlistHandler) MoveToCart(c *gin.Context) {
+	id, err := parseWishlistID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := parseCartItemKey(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	quantity, capped, err := h.service.MoveToCart(c.Request.Context(), getUserID(c), id, item)
+	if err != nil {
+		respondWishlistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "item moved to cart", "quantity": quantity, "capped": capped})
+}
+
+// SaveForLater висит на маршрутах корзины, где вход необязателен, а
+// отложить товар может только вошедший пользователь.
+func (h *WishlistHandler) SaveForLater(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "log in to save items for later"})
+		return
+	}
+
+	item, err := parseCartItemKey(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req models.SaveForLaterRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	wishlistID, err := h.service.SaveForLater(c.Request.Context(), userID, item, req.WishlistID)
+	if err != nil {
+		respondWishlistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "item saved for later", "wishlist_id": wishlistID})
+}
+
+func respondWishlistError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "wishlist not found"})
+	case errors.Is(err, services.ErrNotInCart), errors.Is(err, services.ErrNotInWishlist):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		respondCartError(c, err)
+	}
+}
+
+func parseWishlistID(c *gin.Context) (int64, error) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, errors.New("invalid wishlist id")
+	}
+	return id, nil
+}
@@ -29,3 +29,16 @@ func Auth(authService services.AuthService) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// OptionalAuth пропускает запрос без Authorization как анонимный,
+// но неверный токен по-прежнему даёт 401, а не тихий переход в гости.
+func OptionalAuth(authService services.AuthService) gin.HandlerFunc {
+	required := Auth(authService)
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		required(c)
+	}
+}
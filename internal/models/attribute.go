@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+const (
+	AttributeString  = "string"
+	AttributeNumber  = "number"
+	AttributeBoolean = "boolean"
+	AttributeEnum    = "enum"
+)
+
+type Attribute struct {
+	ID         int64     `json:"id"`
+	Code       string    `json:"code"`
+	Name       string    `json:"name"`
+	Type       string    `json:"type"`
+	Options    []string  `json:"options,omitempty"`
+	Filterable bool      `json:"filterable"`
+	Position   int       `json:"position"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type CreateAttributeRequest struct {
+	Code       string   `json:"code" binding:"required,max=64"`
+	Name       string   `json:"name" binding:"required"`
+	Type       string   `json:"type" binding:"required,oneof=string number boolean enum"`
+	Options    []string `json:"options" binding:"omitempty,dive,required"`
+	Filterable *bool    `json:"filterable"`
+	Position   int      `json:"position"`
+}
+
+// SetProductAttributesRequest заменяет все атрибуты товара. Значения
+// проверяются по типам из определений атрибутов.
+type SetProductAttributesRequest struct {
+	Attributes map[string]any `json:"attributes" binding:"required"`
+}
+
+// AttributeFilter — условие вида attr[code]=v или attr[code][gte]=v из
+// query-строки. Type заполняет сервис по определению атрибута.
+type AttributeFilter struct {
+	Code   string
+	Op     string
+	Values []string
+	Type   string
+}
+
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+type Facet struct {
+	Code   string       `json:"code"`
+	Name   string       `json:"name"`
+	Type   string       `json:"type"`
+	Values []FacetValue `json:"values"`
+}
+
+type FacetsResponse struct {
+	Facets []*Facet `json:"facets"`
+}
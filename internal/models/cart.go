@@ -2,18 +2,125 @@ package models
 
 import "time"
 
+// CartItemKey идентифицирует позицию корзины: товар без вариантов
+// хранится с VariantID = 0.
+type CartItemKey struct {
+	ProductID int64
+	VariantID int64
+}
+
+// CartLine — позиция корзины в хранилище. Price — цена за единицу на момент
+// добавления; 0 у позиций, сохранённых до появления снимка цены.
+type CartLine struct {
+	Quantity int
+	Price    float64
+}
+
+// CartOwner — владелец корзины: пользователь или гость. У гостя
+// UserID = 0, а GuestID берётся из подписанного токена корзины.
+type CartOwner struct {
+	UserID  int64
+	GuestID string
+}
+
+func UserCart(userID int64) CartOwner {
+	return CartOwner{UserID: userID}
+}
+
+func GuestCart(guestID string) CartOwner {
+	return CartOwner{GuestID: guestID}
+}
+
+func (o CartOwner) IsGuest() bool {
+	return o.UserID == 0
+}
+
+// Стратегии слияния гостевой корзины с корзиной пользователя при входе.
+const (
+	CartMergeSum        = "sum"  // количества складываются
+	CartMergeMax        = "max"  // остаётся большее из двух количеств
+	CartMergePreferUser = "user" // позиции, уже лежащие в корзине пользователя, не трогаем
+)
+
 type CartResponseItem struct {
-	ProductID   int64   `json:"product_id"`
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
-	Quantity    int     `json:"quantity"`
-	Subtotal    float64 `json:"subtotal"`
+	ProductID   int64             `json:"product_id"`
+	VariantID   *int64            `json:"variant_id,omitempty"`
+	SKU         string            `json:"sku,omitempty"`
+	Options     map[string]string `json:"options,omitempty"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	ImageURL    string            `json:"image_url,omitempty"`
+	Price       float64           `json:"price"`
+	Quantity    int               `json:"quantity"`
+	Subtotal    float64           `json:"subtotal"`
+	// AddedPrice — цена на момент добавления в корзину, если она отличается от Price.
+	AddedPrice   float64 `json:"added_price,omitempty"`
+	PriceChanged bool    `json:"price_changed"`
+}
+
+// Коды предупреждений корзины.
+const (
+	CartWarningProductRemoved    = "product_removed"
+	CartWarningOutOfStock        = "out_of_stock"
+	CartWarningInsufficientStock = "insufficient_stock"
+	CartWarningPriceChanged      = "price_changed"
+)
+
+// CartWarning сообщает о проблеме с позицией, которую стоит показать
+// покупателю до оформления заказа.
+type CartWarning struct {
+	Code      string  `json:"code"`
+	ProductID int64   `json:"product_id"`
+	VariantID *int64  `json:"variant_id,omitempty"`
+	Message   string  `json:"message"`
+	Available int     `json:"available,omitempty"`
+	OldPrice  float64 `json:"old_price,omitempty"`
+	NewPrice  float64 `json:"new_price,omitempty"`
 }
 
 type CartResponse struct {
 	Items     []CartResponseItem `json:"items"`
+	Warnings  []CartWarning      `json:"warnings"`
 	Total     float64            `json:"total"`
 	ItemCount int                `json:"item_count"`
-	UpdatedAt time.Time          `json:"updated_at,omitempty"`
+	// Checksum считается по позициям, количествам и текущим ценам. Клиент
+	// передаёт его в POST /orders как подтверждение, что видел эти цены.
+	Checksum  string    `json:"checksum"`
+	Version   int64     `json:"version"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+	// Recommendations заполняется только по запросу ?recommendations=N.
+	Recommendations []*RelatedProduct `json:"recommendations,omitempty"`
+}
+
+// SharedCartItem — позиция в копии корзины, которой поделились по ссылке.
+// Цены не сохраняются: при импорте берутся текущие.
+type SharedCartItem struct {
+	ProductID int64 `json:"product_id"`
+	VariantID int64 `json:"variant_id,omitempty"`
+	Quantity  int   `json:"quantity"`
+}
+
+type CartShareResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CartImportResponse — итог импорта: Warnings перечисляет позиции, которые
+// не удалось добавить целиком.
+type CartImportResponse struct {
+	Imported int           `json:"imported"`
+	Warnings []CartWarning `json:"warnings"`
+}
+
+// IdleCart — корзина пользователя, о которой пора напомнить.
+type IdleCart struct {
+	UserID    int64
+	Email     string
+	UpdatedAt time.Time
+	// RemindedAt — время предыдущего напоминания, до захвата корзины.
+	RemindedAt *time.Time
+}
+
+type CartRemindersRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
 }
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+type Category struct {
+	ID        int64       `json:"id"`
+	ParentID  *int64      `json:"parent_id"`
+	Name      string      `json:"name"`
+	Slug      string      `json:"slug"`
+	Path      string      `json:"-"`
+	Position  int         `json:"position"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	Children  []*Category `json:"children"`
+}
+
+type CreateCategoryRequest struct {
+	ParentID *int64 `json:"parent_id"`
+	Name     string `json:"name" binding:"required"`
+	Slug     string `json:"slug" binding:"required"`
+	Position int    `json:"position"`
+}
+
+type UpdateCategoryRequest struct {
+	Name     *string `json:"name" binding:"omitempty,min=1"`
+	Slug     *string `json:"slug" binding:"omitempty,min=1"`
+	Position *int    `json:"position"`
+	// MoveToRoot нужен, чтобы отличить перенос в корень от отсутствия parent_id.
+	ParentID   *int64 `json:"parent_id"`
+	MoveToRoot bool   `json:"move_to_root"`
+}
+
+type CategoryProductsRequest struct {
+	ProductIDs []int64 `json:"product_ids" binding:"required,min=1"`
+}
+
+type CategoryProductsParams struct {
+	Limit int   `form:"limit" binding:"omitempty,min=1,max=100"`
+	After int64 `form:"after" binding:"omitempty,gte=0"`
+}
+
+type CategoryProductsResponse struct {
+	Items     []*Product `json:"items"`
+	NextAfter int64      `json:"next_after,omitempty"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// ExternalProduct — товар из import.xml, ExternalID — его Ид в 1С.
+type ExternalProduct struct {
+	ExternalID  string
+	SKU         string
+	Name        string
+	Description string
+}
+
+// ExternalOffer — цена и остаток из offers.xml. Nil означает, что 1С
+// не передала значение и его не нужно трогать.
+type ExternalOffer struct {
+	ExternalID string
+	Price      *float64
+	Quantity   *int
+}
+
+type ExportOrderItem struct {
+	ProductID       int64
+	ExternalID      string
+	SKU             string
+	Name            string
+	Quantity        int
+	PriceAtPurchase float64
+}
+
+type ExportOrder struct {
+	ID          int64
+	UserID      int64
+	Email       string
+	Status      string
+	TotalAmount float64
+	CreatedAt   time.Time
+	Items       []ExportOrderItem
+}
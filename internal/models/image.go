@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type ProductImage struct {
+	ID           int64     `json:"id"`
+	ProductID    int64     `json:"product_id"`
+	FileKey      string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+	ContentType  string    `json:"content_type"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	AltText      string    `json:"alt_text"`
+	Position     int       `json:"position"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type UpdateProductImageRequest struct {
+	AltText  *string `json:"alt_text"`
+	Position *int    `json:"position" binding:"omitempty,gte=0"`
+}
@@ -2,45 +2,98 @@ package models
 
 import "time"
 
+type OrderStatus string
+
+const (
+	OrderPending         OrderStatus = "pending"
+	OrderAwaitingPayment OrderStatus = "awaiting_payment"
+	OrderPaid            OrderStatus = "paid"
+	OrderProcessing      OrderStatus = "processing"
+	OrderShipped         OrderStatus = "shipped"
+	OrderDelivered       OrderStatus = "delivered"
+	OrderCanceled        OrderStatus = "canceled"
+	OrderRefunded        OrderStatus = "refunded"
+)
+
+// Источники смены статуса заказа для истории.
+const (
+	OrderSourceCustomer       = "customer"
+	OrderSourcePaymentWebhook = "payment_webhook"
+	OrderSourceSystem         = "system"
+	OrderSourceAdmin          = "admin"
+)
+
 type Order struct {
-	ID          int64     `json:"id"`
-	UserID      int64     `json:"user_id"`
-	Status      string    `json:"status"`
-	TotalAmount float64   `json:"total_amount"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          int64       `json:"id"`
+	UserID      int64       `json:"user_id"`
+	Status      OrderStatus `json:"status"`
+	TotalAmount float64     `json:"total_amount"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+// OrderStatusChange — кто или что меняет статус заказа. ActorID — id
+// пользователя, если статус меняет покупатель.
+type OrderStatusChange struct {
+	Source  string
+	ActorID *int64
+	Comment string
+}
+
+type OrderStatusHistoryEntry struct {
+	FromStatus *OrderStatus `json:"from_status"`
+	ToStatus   OrderStatus  `json:"to_status"`
+	Source     string       `json:"source"`
+	Comment    string       `json:"comment,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+type UpdateOrderStatusRequest struct {
+	Status  OrderStatus `json:"status" binding:"required,oneof=pending awaiting_payment paid processing shipped delivered canceled refunded"`
+	Comment string      `json:"comment" binding:"max=1000"`
 }
 
 type OrderItem struct {
 	ID              int64   `json:"id"`
 	OrderID         int64   `json:"-"`
 	ProductID       int64   `json:"product_id"`
+	VariantID       *int64  `json:"variant_id,omitempty"`
 	Quantity        int     `json:"quantity"`
 	PriceAtPurchase float64 `json:"price_at_purchase"`
 }
 
 type OrderResponseItem struct {
-	ProductID   int64   `json:"product_id"`
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
-	Quantity    int     `json:"quantity"`
-	Subtotal    float64 `json:"subtotal"`
+	ProductID   int64             `json:"product_id"`
+	VariantID   *int64            `json:"variant_id,omitempty"`
+	SKU         string            `json:"sku,omitempty"`
+	Options     map[string]string `json:"options,omitempty"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	ImageURL    string            `json:"image_url,omitempty"`
+	Price       float64           `json:"price"`
+	Quantity    int               `json:"quantity"`
+	Subtotal    float64           `json:"subtotal"`
 }
 
 type OrderResponse struct {
 	ID          int64               `json:"id"`
-	Status      string              `json:"status"`
+	Status      OrderStatus         `json:"status"`
 	TotalAmount float64             `json:"total_amount"`
 	Items       []OrderResponseItem `json:"items"`
 	CreatedAt   time.Time           `json:"created_at"`
 	UpdatedAt   time.Time           `json:"updated_at"`
 }
 
+type CreateOrderRequest struct {
+	// CartChecksum обязателен, если цена какой-то позиции изменилась
+	// с момента добавления в корзину.
+	CartChecksum string `json:"cart_checksum"`
+}
+
 type CreateOrderResponse struct {
-	OrderID     int64   `json:"order_id"`
-	Status      string  `json:"status"`
-	TotalAmount float64 `json:"total_amount"`
-	PaymentURL  string  `json:"payment_url"`
-	Message     string  `json:"message"`
+	OrderID     int64       `json:"order_id"`
+	Status      OrderStatus `json:"status"`
+	TotalAmount float64     `json:"total_amount"`
+	PaymentURL  string      `json:"payment_url"`
+	Message     string      `json:"message"`
 }
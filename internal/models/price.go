@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+const (
+	ScheduledPricePending  = "pending"
+	ScheduledPriceApplied  = "applied"
+	ScheduledPriceCanceled = "canceled"
+)
+
+type PriceHistoryEntry struct {
+	Price     float64   `json:"price"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+type PriceHistoryParams struct {
+	From  *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit int        `form:"limit" binding:"omitempty,min=1,max=1000"`
+}
+
+// PriceHistoryResponse.Lowest30Days — минимальная цена, действовавшая за
+// последние 30 дней: от неё считается честная «старая цена» в акциях.
+type PriceHistoryResponse struct {
+	Items        []*PriceHistoryEntry `json:"items"`
+	Lowest30Days *float64             `json:"lowest_30_days,omitempty"`
+}
+
+type ScheduledPrice struct {
+	ID        int64      `json:"id"`
+	ProductID int64      `json:"product_id"`
+	Price     float64    `json:"price"`
+	ApplyAt   time.Time  `json:"apply_at"`
+	Status    string     `json:"status"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type SchedulePriceRequest struct {
+	Price   float64   `json:"price" binding:"required,gt=0"`
+	ApplyAt time.Time `json:"apply_at" binding:"required"`
+}
@@ -3,18 +3,69 @@ package models
 import "time"
 
 type Product struct {
-	ID          int64     `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Price       float64   `json:"price"`
-	Inventory   int       `json:"inventory"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          int64             `json:"id"`
+	SKU         string            `json:"sku,omitempty"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Price       float64           `json:"price"`
+	Inventory   int               `json:"inventory"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	DeletedAt   *time.Time        `json:"deleted_at,omitempty"`
+	Rating      float64           `json:"rating"`
+	ReviewCount int               `json:"review_count"`
+	CategoryIDs []int64           `json:"category_ids,omitempty"`
+	Attributes  map[string]any    `json:"attributes,omitempty"`
+	Options     []*ProductOption  `json:"options,omitempty"`
+	Variants    []*ProductVariant `json:"variants,omitempty"`
+	Images      []*ProductImage   `json:"images,omitempty"`
+	// PurchaseRules заполняется только в карточке товара.
+	PurchaseRules *PurchaseRules `json:"purchase_rules,omitempty"`
 }
 
 type CreateProductRequest struct {
-	Name        string  `json:"name" binding:"required"`
+	SKU         string  `json:"sku" binding:"max=100"`
+	Name        string  `json:"name" binding:"required,max=255"`
 	Description string  `json:"description"`
-	Price       float64 `json:"price" binding:"required,gt=0"`
+	Price       float64 `json:"price" binding:"required,gt=0,lte=99999999.99"`
 	Inventory   int     `json:"inventory" binding:"gte=0"`
 }
+
+type UpdateProductRequest struct {
+	SKU         *string  `json:"sku" binding:"omitempty,min=1,max=100"`
+	Name        *string  `json:"name" binding:"omitempty,min=1,max=255"`
+	Description *string  `json:"description"`
+	Price       *float64 `json:"price" binding:"omitempty,gt=0,lte=99999999.99"`
+	Inventory   *int     `json:"inventory" binding:"omitempty,gte=0"`
+}
+
+type ProductListParams struct {
+	Limit    int      `form:"limit" binding:"omitempty,min=1,max=100"`
+	After    string   `form:"after"`
+	MinPrice *float64 `form:"min_price" binding:"omitempty,gte=0"`
+	MaxPrice *float64 `form:"max_price" binding:"omitempty,gte=0"`
+	InStock  *bool    `form:"in_stock"`
+	Query    string   `form:"q"`
+	Sort     string   `form:"sort" binding:"omitempty,oneof=price created_at name"`
+	Order    string   `form:"order" binding:"omitempty,oneof=asc desc"`
+	// Attributes разбирается из attr[...] вручную: gin не биндит такие ключи.
+	Attributes []AttributeFilter `form:"-"`
+}
+
+type ProductListResponse struct {
+	Items      []*Product `json:"items"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+	Total      int64      `json:"total"`
+}
+
+type ProductSearchParams struct {
+	Query string `form:"q" binding:"required"`
+	Limit int    `form:"limit" binding:"omitempty,min=1,max=50"`
+}
+
+type ProductSearchResult struct {
+	Product
+	Rank          float64 `json:"rank"`
+	NameHighlight string  `json:"name_highlight"`
+	Snippet       string  `json:"snippet"`
+}
@@ -0,0 +1,55 @@
+package models
+
+const (
+	ImportCreated   = "created"
+	ImportUpdated   = "updated"
+	ImportUnchanged = "unchanged"
+	// ImportDeleted — товар с этим SKU удалён администратором, импорт
+	// его не восстанавливает.
+	ImportDeleted = "deleted"
+)
+
+// ImportProduct — строка импорта. Nil означает, что колонки нет в файле
+// и у существующего товара это значение не меняется.
+type ImportProduct struct {
+	SKU         string
+	Name        string
+	Description *string
+	Price       float64
+	Inventory   *int
+}
+
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	SKU   string `json:"sku,omitempty"`
+	Error string `json:"error"`
+}
+
+type ImportReport struct {
+	DryRun    bool             `json:"dry_run"`
+	TotalRows int              `json:"total_rows"`
+	Created   int              `json:"created"`
+	Updated   int              `json:"updated"`
+	Unchanged int              `json:"unchanged"`
+	Failed    int              `json:"failed"`
+	Errors    []ImportRowError `json:"errors"`
+}
+
+// Count учитывает результаты UpsertBySKU.
+func (r *ImportReport) Count(statuses ...string) {
+	for _, status := range statuses {
+		switch status {
+		case ImportCreated:
+			r.Created++
+		case ImportUpdated:
+			r.Updated++
+		case ImportUnchanged:
+			r.Unchanged++
+		}
+	}
+}
+
+func (r *ImportReport) AddError(row int, sku string, message string) {
+	r.Failed++
+	r.Errors = append(r.Errors, ImportRowError{Row: row, SKU: sku, Error: message})
+}
@@ -0,0 +1,29 @@
+package models
+
+// PurchaseRules ограничивают количество товара в корзине и заказе.
+// MinQuantity и QuantityStep относятся к каждой позиции (варианту) отдельно,
+// MaxQuantity и CustomerLimit — ко всем вариантам товара вместе.
+type PurchaseRules struct {
+	MinQuantity  int  `json:"min_quantity"`
+	MaxQuantity  *int `json:"max_quantity,omitempty"`
+	QuantityStep int  `json:"quantity_step"`
+	// CustomerLimit — сколько штук один покупатель может купить за CustomerLimitDays дней.
+	CustomerLimit     *int `json:"customer_limit,omitempty"`
+	CustomerLimitDays *int `json:"customer_limit_days,omitempty"`
+}
+
+type SetPurchaseRulesRequest struct {
+	MinQuantity       int  `json:"min_quantity" binding:"omitempty,min=1"`
+	MaxQuantity       *int `json:"max_quantity" binding:"omitempty,min=1"`
+	QuantityStep      int  `json:"quantity_step" binding:"omitempty,min=1"`
+	CustomerLimit     *int `json:"customer_limit" binding:"omitempty,min=1"`
+	CustomerLimitDays *int `json:"customer_limit_days" binding:"omitempty,min=1,max=3650"`
+}
+
+// Коды нарушений правил покупки.
+const (
+	PurchaseRuleMinQuantity   = "min_quantity"
+	PurchaseRuleMaxQuantity   = "max_quantity"
+	PurchaseRuleQuantityStep  = "quantity_step"
+	PurchaseRuleCustomerLimit = "customer_limit"
+)
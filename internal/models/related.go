@@ -0,0 +1,27 @@
+package models
+
+const (
+	RelatedCurated        = "curated"
+	RelatedBoughtTogether = "bought_together"
+)
+
+type RelatedProduct struct {
+	Product
+	Source string `json:"source"`
+}
+
+// ProductLink — связь, заданная вручную или посчитанная по заказам.
+// Score для ручных связей — позиция, для совместных покупок — число заказов.
+type ProductLink struct {
+	ProductID int64
+	RelatedID int64
+	Score     int
+}
+
+type RelatedProductsParams struct {
+	Limit int `form:"limit" binding:"omitempty,min=1,max=50"`
+}
+
+type SetRelatedProductsRequest struct {
+	ProductIDs []int64 `json:"product_ids" binding:"required,dive,gt=0"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+)
+
+type Review struct {
+	ID          int64      `json:"id"`
+	ProductID   int64      `json:"product_id"`
+	UserID      int64      `json:"user_id"`
+	Rating      int        `json:"rating"`
+	Body        string     `json:"body"`
+	Status      string     `json:"status"`
+	ModeratedAt *time.Time `json:"moderated_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type CreateReviewRequest struct {
+	Rating int    `json:"rating" binding:"required,min=1,max=5"`
+	Body   string `json:"body" binding:"max=5000"`
+}
+
+type ReviewListParams struct {
+	Limit int   `form:"limit" binding:"omitempty,min=1,max=100"`
+	After int64 `form:"after" binding:"omitempty,gte=0"`
+}
+
+type ModerationListParams struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending approved rejected"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	After  int64  `form:"after" binding:"omitempty,gte=0"`
+}
+
+type ReviewListResponse struct {
+	Items     []*Review `json:"items"`
+	NextAfter int64     `json:"next_after,omitempty"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+type ProductOption struct {
+	ID        int64    `json:"id"`
+	ProductID int64    `json:"-"`
+	Name      string   `json:"name"`
+	Values    []string `json:"values"`
+	Position  int      `json:"position"`
+}
+
+type ProductVariant struct {
+	ID        int64             `json:"id"`
+	ProductID int64             `json:"product_id"`
+	SKU       string            `json:"sku"`
+	Price     *float64          `json:"price,omitempty"`
+	Inventory int               `json:"inventory"`
+	Options   map[string]string `json:"options"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// PriceFor возвращает цену варианта с учётом того, что override может быть не задан.
+func (v *ProductVariant) PriceFor(p *Product) float64 {
+	if v.Price != nil {
+		return *v.Price
+	}
+	return p.Price
+}
+
+type ProductOptionInput struct {
+	Name   string   `json:"name" binding:"required"`
+	Values []string `json:"values" binding:"required,min=1,dive,required"`
+}
+
+type SetProductOptionsRequest struct {
+	Options []ProductOptionInput `json:"options" binding:"dive"`
+}
+
+type CreateVariantRequest struct {
+	SKU       string            `json:"sku" binding:"required"`
+	Price     *float64          `json:"price" binding:"omitempty,gt=0"`
+	Inventory int               `json:"inventory" binding:"gte=0"`
+	Options   map[string]string `json:"options" binding:"required"`
+}
+
+type UpdateVariantRequest struct {
+	SKU       *string  `json:"sku" binding:"omitempty,min=1"`
+	Price     *float64 `json:"price" binding:"omitempty,gt=0"`
+	Inventory *int     `json:"inventory" binding:"omitempty,gte=0"`
+}
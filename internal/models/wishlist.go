@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// DefaultWishlistName — имя списка «Отложено», который создаётся
+// автоматически, когда товар впервые откладывают из корзины.
+const DefaultWishlistName = "Saved for later"
+
+type Wishlist struct {
+	ID        int64  `json:"id"`
+	UserID    int64  `json:"-"`
+	Name      string `json:"name"`
+	IsDefault bool   `json:"is_default"`
+	// ShareToken задан только у опубликованного списка.
+	ShareToken *string        `json:"share_token,omitempty"`
+	ItemCount  int            `json:"item_count"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	Items      []WishlistItem `json:"items,omitempty"`
+}
+
+// WishlistItem — отложенный товар. Цена и остаток не хранятся в списке,
+// а подставляются текущие при каждом чтении.
+type WishlistItem struct {
+	ProductID int64             `json:"product_id"`
+	VariantID *int64            `json:"variant_id,omitempty"`
+	Quantity  int               `json:"quantity"`
+	AddedAt   time.Time         `json:"added_at"`
+	Name      string            `json:"name"`
+	SKU       string            `json:"sku,omitempty"`
+	Options   map[string]string `json:"options,omitempty"`
+	ImageURL  string            `json:"image_url,omitempty"`
+	Price     float64           `json:"price"`
+	Inventory int               `json:"inventory"`
+	InStock   bool              `json:"in_stock"`
+	// Available = false, если товар или вариант удалён из каталога.
+	Available bool `json:"available"`
+}
+
+type CreateWishlistRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+type UpdateWishlistRequest struct {
+	Name *string `json:"name" binding:"omitempty,min=1,max=100"`
+	// Public публикует список по ссылке или снимает публикацию.
+	Public *bool `json:"public"`
+}
+
+type AddWishlistItemRequest struct {
+	ProductID int64 `json:"product_id" binding:"required"`
+	VariantID int64 `json:"variant_id" binding:"gte=0"`
+	Quantity  int   `json:"quantity" binding:"omitempty,gt=0"`
+}
+
+// SaveForLaterRequest — в какой список отложить позицию корзины;
+// без WishlistID используется список «Отложено».
+type SaveForLaterRequest struct {
+	WishlistID int64 `json:"wishlist_id" binding:"gte=0"`
+}
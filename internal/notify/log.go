@@ -0,0 +1,18 @@
+package notify
+
+import (
+	"context"
+	"log"
+)
+
+type logNotifier struct{}
+
+// NewLogNotifier только пишет письма в лог — для разработки, когда SMTP не настроен.
+func NewLogNotifier() Notifier {
+	return logNotifier{}
+}
+
+func (logNotifier) Send(ctx context.Context, msg Message) error {
+	log.Printf("notification to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
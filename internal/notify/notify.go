@@ -0,0 +1,14 @@
+package notify
+
+import "context"
+
+type Message struct {
+	To      string
+	Subject string
+	// Body — обычный текст в UTF-8.
+	Body string
+}
+
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
@@ -0,0 +1,183 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTPServer — минимальный SMTP-сервер без TLS и авторизации:
+// принимает одно письмо и запоминает конверт и данные.
+type fakeSMTPServer struct {
+	listener net.Listener
+	// rejectRcpt — отвечать 550 на RCPT TO.
+	rejectRcpt bool
+
+	from string
+	rcpt []string
+	data string
+	done chan struct{}
+}
+
+func startFakeSMTPServer(t *testing.T, rejectRcpt bool) *fakeSMTPServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &fakeSMTPServer{listener: listener, rejectRcpt: rejectRcpt, done: make(chan struct{})}
+	go s.serve(t)
+	return s
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTPServer) serve(t *testing.T) {
+	defer close(s.done)
+
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) {
+		io.WriteString(conn, line+"\r\n")
+	}
+
+	reply("220 localhost ESMTP fake")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250-localhost")
+			reply("250 8BITMIME")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			s.from = envelopeAddress(line[len("MAIL FROM:"):])
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			if s.rejectRcpt {
+				reply("550 no such user")
+				continue
+			}
+			s.rcpt = append(s.rcpt, envelopeAddress(line[len("RCPT TO:"):]))
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 end with <CRLF>.<CRLF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+			s.data = data.String()
+			reply("250 OK queued")
+		case cmd == "RSET", cmd == "NOOP":
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			t.Errorf("fake smtp: unexpected command %q", line)
+			reply("502 not implemented")
+		}
+	}
+}
+
+// envelopeAddress достаёт адрес из "<addr> BODY=8BITMIME".
+func envelopeAddress(arg string) string {
+	addr, _, _ := strings.Cut(strings.TrimSpace(arg), " ")
+	return strings.Trim(addr, "<>")
+}
+
+func TestSMTPNotifierSend(t *testing.T) {
+	server := startFakeSMTPServer(t, false)
+	notifier := NewSMTPNotifier("127.0.0.1", server.port(), "", "", "Shop <shop@example.com>")
+
+	msg := Message{
+		To:      "buyer@example.com",
+		Subject: "Корзина ждёт",
+		Body:    "You still have items waiting in your cart:\n\n- Чайник x 2\n",
+	}
+	if err := notifier.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	<-server.done
+
+	if server.from != "shop@example.com" {
+		t.Errorf("MAIL FROM = %q, want shop@example.com", server.from)
+	}
+	if len(server.rcpt) != 1 || server.rcpt[0] != "buyer@example.com" {
+		t.Errorf("RCPT TO = %v, want [buyer@example.com]", server.rcpt)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(server.data))
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("decode subject: %v", err)
+	}
+	if subject != msg.Subject {
+		t.Errorf("Subject = %q, want %q", subject, msg.Subject)
+	}
+	if got := parsed.Header.Get("Content-Transfer-Encoding"); got != "quoted-printable" {
+		t.Errorf("Content-Transfer-Encoding = %q, want quoted-printable", got)
+	}
+
+	body, err := io.ReadAll(quotedprintable.NewReader(parsed.Body))
+	if err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	// SMTP передаёт строки с CRLF.
+	if got := strings.ReplaceAll(string(body), "\r\n", "\n"); got != msg.Body {
+		t.Errorf("body = %q, want %q", got, msg.Body)
+	}
+}
+
+func TestSMTPNotifierRejectedRecipient(t *testing.T) {
+	server := startFakeSMTPServer(t, true)
+	notifier := NewSMTPNotifier("127.0.0.1", server.port(), "", "", "shop@example.com")
+
+	err := notifier.Send(context.Background(), Message{To: "nobody@example.com", Subject: "hi", Body: "hi"})
+	if err == nil || !strings.Contains(err.Error(), "550") {
+		t.Fatalf("Send error = %v, want 550 from server", err)
+	}
+	<-server.done
+}
+
+func TestSMTPNotifierInvalidRecipient(t *testing.T) {
+	notifier := NewSMTPNotifier("127.0.0.1", 25, "", "", "shop@example.com")
+
+	err := notifier.Send(context.Background(), Message{To: "not an address", Subject: "hi", Body: "hi"})
+	if err == nil || !strings.Contains(err.Error(), "invalid recipient") {
+		t.Fatalf("Send error = %v, want invalid recipient", err)
+	}
+}
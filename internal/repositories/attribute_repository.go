@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"context"
+	"ecommerce-api/internal/models"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrAttributeCodeTaken = errors.New("attribute code already exists")
+
+type AttributeRepository interface {
+	Create(ctx context.Context, attr *models.Attribute) error
+	List(ctx context.Context) ([]*models.Attribute, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+type attributeRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAttributeRepository(pool *pgxpool.Pool) AttributeRepository {
+	return &attributeRepository{pool: pool}
+}
+
+func (r *attributeRepository) Create(ctx context.Context, attr *models.Attribute) error {
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO attributes (code, name, type, options, filterable, position)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`,
+		attr.Code, attr.Name, attr.Type, attr.Options, attr.Filterable, attr.Position,
+	).Scan(&attr.ID, &attr.CreatedAt)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrAttributeCodeTaken
+	}
+	return err
+}
+
+func (r *attributeRepository) List(ctx context.Context) ([]*models.Attribute, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, code, name, type, options, filterable, position, created_at
+		FROM attributes
+		ORDER BY position, name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attributes := make([]*models.Attribute, 0)
+	for rows.Next() {
+		a := &models.Attribute{}
+		if err := rows.Scan(&a.ID, &a.Code, &a.Name, &a.Type, &a.Options, &a.Filterable, &a.Position, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attributes, nil
+}
+
+// Delete удаляет определение и убирает значение атрибута из всех товаров.
+func (r *attributeRepository) Delete(ctx context.Context, id int64) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var code string
+	if err := tx.QueryRow(ctx, `DELETE FROM attributes WHERE id = $1 RETURNING code`, id).Scan(&code); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE products
+		SET attributes = attributes - $1::text,
+			updated_at = NOW()
+		WHERE attributes ? $1::text`, code)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
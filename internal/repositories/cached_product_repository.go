@@ -0,0 +1,341 @@
+package repositories
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha1"
+	"ecommerce-api/internal/models"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	productCacheTTL     = 5 * time.Minute
+	productListCacheTTL = time.Minute
+	productCachePrefix  = "catalog:product:"
+	productListPrefix   = "catalog:list:"
+	// productListGenKey входит в ключи страниц списка: любое изменение
+	// каталога увеличивает его, и старые страницы просто перестают читаться.
+	productListGenKey = "catalog:list_gen"
+	// productCacheRetryAfter — как долго после ошибки Redis чтения идут
+	// сразу в Postgres, не дожидаясь таймаута Redis на каждом запросе.
+	productCacheRetryAfter = 10 * time.Second
+)
+
+// cachedProductRepository — read-through кэш поверх ProductRepository.
+// Кэшируются отдельные товары и страницы List, остальные методы идут в базу.
+// При недоступном Redis все запросы прозрачно уходят в Postgres.
+type cachedProductRepository struct {
+	ProductRepository
+	rdb   *redis.Client
+	group singleflight.Group
+	// failedAt — время последней ошибки Redis в UnixNano, 0 — работает.
+	failedAt atomic.Int64
+}
+
+// NewCachedProductRepository без Redis возвращает repo как есть.
+func NewCachedProductRepository(repo ProductRepository, rdb *redis.Client) ProductRepository {
+	if rdb == nil {
+		return repo
+	}
+	return &cachedProductRepository{ProductRepository: repo, rdb: rdb}
+}
+
+func productCacheKey(id int64) string {
+	return productCachePrefix + strconv.FormatInt(id, 10)
+}
+
+func (r *cachedProductRepository) GetByID(ctx context.Context, id int64) (*models.Product, error) {
+	key := productCacheKey(id)
+
+	var cached models.Product
+	if r.get(ctx, key, &cached) {
+		return &cached, nil
+	}
+
+	// Запрос в базу не должен отмениться из-за того, что ушёл клиент,
+	// который первым попал в singleflight: его результат ждут остальные.
+	v, err, _ := r.group.Do(key, func() (any, error) {
+		fetchCtx := context.WithoutCancel(ctx)
+		p, err := r.ProductRepository.GetByID(fetchCtx, id)
+		if err != nil {
+			return nil, err
+		}
+		r.set(fetchCtx, key, p, productCacheTTL)
+		return p, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Копия: сервисы дописывают в товар варианты и картинки, а результат
+	// singleflight общий для всех ожидавших.
+	p := *v.(*models.Product)
+	return &p, nil
+}
+
+func (r *cachedProductRepository) GetByIDs(ctx context.Context, ids []int64) ([]*models.Product, error) {
+	if len(ids) == 0 {
+		return []*models.Product{}, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = productCacheKey(id)
+	}
+
+	products := make([]*models.Product, 0, len(ids))
+	var missing []int64
+
+	// values остаётся nil, если Redis пропущен или вернул ошибку.
+	var values []any
+	if r.available() {
+		var err error
+		values, err = r.rdb.MGet(ctx, keys...).Result()
+		r.track(err)
+		if err != nil {
+			values = nil
+		}
+	}
+	for i, id := range ids {
+		if values == nil {
+			missing = append(missing, id)
+			continue
+		}
+		s, ok := values[i].(string)
+		p := &models.Product{}
+		if !ok || json.Unmarshal([]byte(s), p) != nil {
+			missing = append(missing, id)
+			continue
+		}
+		products = append(products, p)
+	}
+
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		flightKey := "ids:" + joinIDs(missing)
+
+		v, err, _ := r.group.Do(flightKey, func() (any, error) {
+			fetchCtx := context.WithoutCancel(ctx)
+			fetched, err := r.ProductRepository.GetByIDs(fetchCtx, missing)
+			if err != nil {
+				return nil, err
+			}
+			r.setMany(fetchCtx, fetched)
+			return fetched, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range v.([]*models.Product) {
+			cp := *p
+			products = append(products, &cp)
+		}
+	}
+
+	// Как и базовый репозиторий, отдаём товары по возрастанию id.
+	slices.SortFunc(products, func(a, b *models.Product) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return products, nil
+}
+
+func (r *cachedProductRepository) List(ctx context.Context, params *models.ProductListParams) (*models.ProductListResponse, error) {
+	if !r.available() {
+		return r.ProductRepository.List(ctx, params)
+	}
+
+	gen, err := r.rdb.Get(ctx, productListGenKey).Result()
+	if errors.Is(err, redis.Nil) {
+		gen, err = "0", nil
+	}
+	r.track(err)
+	if err != nil {
+		return r.ProductRepository.List(ctx, params)
+	}
+
+	data, _ := json.Marshal(params)
+	sum := sha1.Sum(data)
+	key := productListPrefix + gen + ":" + hex.EncodeToString(sum[:])
+
+	var cached models.ProductListResponse
+	if r.get(ctx, key, &cached) {
+		return &cached, nil
+	}
+
+	v, err, _ := r.group.Do(key, func() (any, error) {
+		fetchCtx := context.WithoutCancel(ctx)
+		response, err := r.ProductRepository.List(fetchCtx, params)
+		if err != nil {
+			return nil, err
+		}
+		r.set(fetchCtx, key, response, productListCacheTTL)
+		return response, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Сервис дописывает картинки в товары страницы, поэтому копируем и их.
+	shared := v.(*models.ProductListResponse)
+	response := *shared
+	response.Items = make([]*models.Product, len(shared.Items))
+	for i, p := range shared.Items {
+		cp := *p
+		response.Items[i] = &cp
+	}
+
+	return &response, nil
+}
+
+func (r *cachedProductRepository) Create(ctx context.Context, req *models.CreateProductRequest) (int64, error) {
+	id, err := r.ProductRepository.Create(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+	r.InvalidateCache(ctx, id)
+	return id, nil
+}
+
+func (r *cachedProductRepository) Update(ctx context.Context, id int64, req *models.UpdateProductRequest) (*models.Product, error) {
+	p, err := r.ProductRepository.Update(ctx, id, req)
+	if err != nil {
+		return nil, err
+	}
+	r.InvalidateCache(ctx, id)
+	return p, nil
+}
+
+func (r *cachedProductRepository) Delete(ctx context.Context, id int64) error {
+	if err := r.ProductRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.InvalidateCache(ctx, id)
+	return nil
+}
+
+func (r *cachedProductRepository) SetAttributes(ctx context.Context, id int64, attributes map[string]any) error {
+	if err := r.ProductRepository.SetAttributes(ctx, id, attributes); err != nil {
+		return err
+	}
+	r.InvalidateCache(ctx, id)
+	return nil
+}
+
+// InvalidateCache удаляет товары ids из кэша и сбрасывает все страницы
+// списка. Без ids сбрасывается весь кэш каталога — так делают массовые
+// изменения вроде импорта, где затронутые товары заранее неизвестны.
+func (r *cachedProductRepository) InvalidateCache(ctx context.Context, ids ...int64) {
+	ctx = context.WithoutCancel(ctx)
+
+	var keys []string
+	if len(ids) == 0 {
+		iter := r.rdb.Scan(ctx, 0, productCachePrefix+"*", 500).Iterator()
+		for iter.Next(ctx) {
+			keys = append(keys, iter.Val())
+		}
+		if err := iter.Err(); err != nil {
+			r.track(err)
+			return
+		}
+	} else {
+		for _, id := range ids {
+			keys = append(keys, productCacheKey(id))
+		}
+	}
+
+	pipe := r.rdb.Pipeline()
+	for chunk := range slices.Chunk(keys, 500) {
+		pipe.Unlink(ctx, chunk...)
+	}
+	pipe.Incr(ctx, productListGenKey)
+	_, err := pipe.Exec(ctx)
+	r.track(err)
+}
+
+func (r *cachedProductRepository) get(ctx context.Context, key string, dst any) bool {
+	if !r.available() {
+		return false
+	}
+
+	data, err := r.rdb.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return false
+	}
+	r.track(err)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, dst) == nil
+}
+
+func (r *cachedProductRepository) set(ctx context.Context, key string, value any, ttl time.Duration) {
+	if !r.available() {
+		return
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	r.track(r.rdb.Set(ctx, key, data, ttl).Err())
+}
+
+func (r *cachedProductRepository) setMany(ctx context.Context, products []*models.Product) {
+	if len(products) == 0 || !r.available() {
+		return
+	}
+
+	pipe := r.rdb.Pipeline()
+	for _, p := range products {
+		data, err := json.Marshal(p)
+		if err != nil {
+			continue
+		}
+		pipe.Set(ctx, productCacheKey(p.ID), data, productCacheTTL)
+	}
+	_, err := pipe.Exec(ctx)
+	r.track(err)
+}
+
+// available — можно ли обращаться к Redis. После ошибки чтения и записи
+// кэша пропускаются на productCacheRetryAfter. Инвалидация идёт в Redis
+// всегда: изменения редки, а пропущенная могла бы оставить устаревшие данные.
+func (r *cachedProductRepository) available() bool {
+	failedAt := r.failedAt.Load()
+	return failedAt == 0 || time.Since(time.Unix(0, failedAt)) >= productCacheRetryAfter
+}
+
+// track пишет в лог только смену состояния Redis, чтобы при его падении
+// не получать строку на каждый запрос.
+func (r *cachedProductRepository) track(err error) {
+	if err != nil && !errors.Is(err, redis.Nil) {
+		if r.failedAt.Swap(time.Now().UnixNano()) == 0 {
+			log.Printf("product cache unavailable, falling back to postgres: %v", err)
+		}
+		return
+	}
+	if r.failedAt.Swap(0) != 0 {
+		log.Println("product cache recovered")
+	}
+}
+
+func joinIDs(ids []int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"context"
+	"ecommerce-api/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// CartActivityRepository отслеживает, когда пользователи последний раз меняли
+// корзину и когда им напоминали о ней. Гостевые корзины не отслеживаются:
+// гостю некуда отправить напоминание.
+type CartActivityRepository interface {
+	Touch(ctx context.Context, userID int64) error
+	// ClaimIdle выбирает корзины, которые не менялись с idleBefore и ещё
+	// не истекли, если после последнего изменения пользователь не оформлял
+	// заказ, не отписался от напоминаний, ещё не получал напоминания об этих
+	// изменениях и не получал никаких напоминаний после remindedBefore,
+	// и сразу отмечает их напомненными. SKIP LOCKED позволяет запускать
+	// рассылку на нескольких инстансах: каждую корзину захватит только один.
+	ClaimIdle(ctx context.Context, idleBefore time.Time, remindedBefore time.Time, limit int) ([]*models.IdleCart, error)
+	// Release снимает захват с корзины, напоминание о которой не ушло,
+	// чтобы она попала в следующий проход.
+	Release(ctx context.Context, cart *models.IdleCart) error
+	Delete(ctx context.Context, userID int64) error
+	// DeleteExpired удаляет записи о корзинах, которые уже истекли.
+	DeleteExpired(ctx context.Context) (int64, error)
+	SetRemindersEnabled(ctx context.Context, userID int64, enabled bool) error
+}
+
+type cartActivityRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewCartActivityRepository(pool *pgxpool.Pool) CartActivityRepository {
+	return &cartActivityRepository{pool: pool}
+}
+
+func (r *cartActivityRepository) Touch(ctx context.Context, userID int64) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO cart_activity (user_id)
+		VALUES ($1)
+		ON CONFLICT (user_id) DO UPDATE
+		SET updated_at = NOW()`, userID)
+	return err
+}
+
+func (r *cartActivityRepository) ClaimIdle(ctx context.Context, idleBefore time.Time, remindedBefore time.Time, limit int) ([]*models.IdleCart, error) {
+	rows, err := r.pool.Query(ctx, `
+		WITH idle AS (
+			SELECT a.user_id, u.email, a.reminded_at
+			FROM cart_activity a
+			JOIN users u ON u.id = a.user_id
+			WHERE a.updated_at < $1
+				AND a.updated_at >= NOW() - $2::interval
+				AND u.cart_reminders
+				AND (a.reminded_at IS NULL OR (a.reminded_at < a.updated_at AND a.reminded_at < $3))
+				AND NOT EXISTS (
+					SELECT 1 FROM orders o
+					WHERE o.user_id = a.user_id AND o.created_at >= a.updated_at
+				)
+			ORDER BY a.updated_at
+			LIMIT $4
+			FOR UPDATE OF a SKIP LOCKED
+		)
+		UPDATE cart_activity a
+		SET reminded_at = NOW()
+		FROM idle
+		WHERE a.user_id = idle.user_id
+		RETURNING a.user_id, idle.email, a.updated_at, idle.reminded_at`, idleBefore, cartTTL, remindedBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*models.IdleCart, error) {
+		var cart models.IdleCart
+		err := row.Scan(&cart.UserID, &cart.Email, &cart.UpdatedAt, &cart.RemindedAt)
+		return &cart, err
+	})
+}
+
+func (r *cartActivityRepository) Release(ctx context.Context, cart *models.IdleCart) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE cart_activity
+		SET reminded_at = $2
+		WHERE user_id = $1`, cart.UserID, cart.RemindedAt)
+	return err
+}
+
+func (r *cartActivityRepository) Delete(ctx context.Context, userID int64) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM cart_activity WHERE user_id = $1`, userID)
+	return err
+}
+
+func (r *cartActivityRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := r.pool.Exec(ctx, `
+		DELETE FROM cart_activity
+		WHERE updated_at < NOW() - $1::interval`, cartTTL)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+func (r *cartActivityRepository) SetRemindersEnabled(ctx context.Context, userID int64, enabled bool) error {
+	result, err := r.pool.Exec(ctx, `
+		UPDATE users
+		SET cart_reminders = $2,
+			updated_at = NOW()
+		WHERE id = $1`, userID, enabled)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
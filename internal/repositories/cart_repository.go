@@ -2,19 +2,36 @@ package repositories
 
 import (
 	"context"
+	"ecommerce-api/internal/models"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+const cartTTL = 7 * 24 * time.Hour
+
 type CartRepository interface {
-	AddItem(ctx context.Context, userID int64, productID int64, quantity int) error
-	UpdateItem(ctx context.Context, userID int64, productID int64, quantity int) error
-	RemoveItem(ctx context.Context, userID int64, productID int64) error
-	GetCart(ctx context.Context, userID int64) (map[int64]int, error)
-	ClearCart(ctx context.Context, userID int64) error
+	// AddItem увеличивает количество и запоминает price как цену, которую
+	// покупатель видел при добавлении. Цена, запомненная при первом
+	// добавлении позиции, не перезаписывается.
+	AddItem(ctx context.Context, owner models.CartOwner, item models.CartItemKey, quantity int, price float64) error
+	// UpdateItem меняет количество; цена запоминается, только если позиции
+	// ещё не было в корзине.
+	UpdateItem(ctx context.Context, owner models.CartOwner, item models.CartItemKey, quantity int, price float64) error
+	RemoveItem(ctx context.Context, owner models.CartOwner, item models.CartItemKey) error
+	// GetCart возвращает позиции и версию корзины. Версия растёт при каждом
+	// изменении и не сбрасывается при очистке.
+	GetCart(ctx context.Context, owner models.CartOwner) (map[models.CartItemKey]models.CartLine, int64, error)
+	ClearCart(ctx context.Context, owner models.CartOwner) error
+	// ClearCartIfVersion очищает корзину, только если её версия всё ещё
+	// равна version, и сообщает, была ли корзина очищена.
+	ClearCartIfVersion(ctx context.Context, owner models.CartOwner, version int64) (bool, error)
+	// MergeGuestCart переносит гостевую корзину в корзину пользователя
+	// и удаляет гостевую. Возвращает число перенесённых позиций.
+	MergeGuestCart(ctx context.Context, guestID string, userID int64, strategy string) (int, error)
 }
 
 type cartRepository struct {
@@ -25,71 +42,228 @@ func NewCartRepository(rdb *redis.Client) CartRepository {
 	return &cartRepository{rdb: rdb}
 }
 
-func (r *cartRepository) getCartKey(userID int64) string {
-	return fmt.Sprintf("cart:%d", userID)
+// Гостевые корзины живут в отдельном пространстве ключей, чтобы
+// идентификатор гостя никогда не совпал с id пользователя.
+func (r *cartRepository) getCartKey(owner models.CartOwner) string {
+	if owner.IsGuest() {
+		return "cart:guest:" + owner.GuestID
+	}
+	return fmt.Sprintf("cart:%d", owner.UserID)
 }
 
-func (r *cartRepository) setTTL(ctx context.Context, key string) error {
-	return r.rdb.Expire(ctx, key, 7*24*time.Hour).Err()
+// Поле хэша — "productID" для товара без вариантов и "productID:variantID"
+// для варианта, так что корзины, сохранённые до появления вариантов, читаются как раньше.
+func cartField(item models.CartItemKey) string {
+	if item.VariantID == 0 {
+		return strconv.FormatInt(item.ProductID, 10)
+	}
+	return strconv.FormatInt(item.ProductID, 10) + ":" + strconv.FormatInt(item.VariantID, 10)
 }
 
-func (r *cartRepository) AddItem(ctx context.Context, userID int64, productID int64, quantity int) error {
-	key := r.getCartKey(userID)
-	field := strconv.Itoa(int(productID))
+// Цена на момент добавления лежит в том же хэше под полем "price:<поле позиции>",
+// а версия корзины — под полем "_version", чтобы TTL, очистка и слияние
+// работали с одним ключом.
+const (
+	cartPricePrefix  = "price:"
+	cartVersionField = "_version"
+)
 
-	if err := r.rdb.HIncrBy(ctx, key, field, int64(quantity)).Err(); err != nil {
-		return err
-	}
-	return r.setTTL(ctx, key)
+func cartPriceField(item models.CartItemKey) string {
+	return cartPricePrefix + cartField(item)
 }
 
-func (r *cartRepository) UpdateItem(ctx context.Context, userID int64, productID int64, quantity int) error {
-	key := r.getCartKey(userID)
-	field := strconv.Itoa(int(productID))
-	if quantity <= 0 {
-		return r.RemoveItem(ctx, userID, productID)
+func parseCartField(field string) (models.CartItemKey, error) {
+	productPart, variantPart, hasVariant := strings.Cut(field, ":")
+
+	productID, err := strconv.ParseInt(productPart, 10, 64)
+	if err != nil {
+		return models.CartItemKey{}, err
 	}
 
-	if err := r.rdb.HSet(ctx, key, field, quantity).Err(); err != nil {
-		return err
+	item := models.CartItemKey{ProductID: productID}
+	if hasVariant {
+		item.VariantID, err = strconv.ParseInt(variantPart, 10, 64)
+		if err != nil {
+			return models.CartItemKey{}, err
+		}
 	}
+	return item, nil
+}
+
+// Каждое изменение корзины — один Lua-скрипт: количество, цена, версия
+// и TTL меняются атомарно и за один round trip.
+var (
+	// KEYS[1] — корзина; ARGV: поле позиции, поле цены, количество, цена, TTL.
+	addCartItemScript = redis.NewScript(`
+redis.call('HINCRBY', KEYS[1], ARGV[1], ARGV[3])
+redis.call('HSETNX', KEYS[1], ARGV[2], ARGV[4])
+local version = redis.call('HINCRBY', KEYS[1], '_version', 1)
+redis.call('EXPIRE', KEYS[1], ARGV[5])
+return version
+`)
+
+	updateCartItemScript = redis.NewScript(`
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[3])
+redis.call('HSETNX', KEYS[1], ARGV[2], ARGV[4])
+local version = redis.call('HINCRBY', KEYS[1], '_version', 1)
+redis.call('EXPIRE', KEYS[1], ARGV[5])
+return version
+`)
+
+	// KEYS[1] — корзина; ARGV: поле позиции, поле цены, TTL.
+	removeCartItemScript = redis.NewScript(`
+if redis.call('HDEL', KEYS[1], ARGV[1], ARGV[2]) == 0 then
+	return redis.call('HGET', KEYS[1], '_version') or 0
+end
+local version = redis.call('HINCRBY', KEYS[1], '_version', 1)
+redis.call('EXPIRE', KEYS[1], ARGV[3])
+return version
+`)
+
+	// Очистка оставляет в хэше только увеличенную версию: иначе после
+	// очистки версия начиналась бы с нуля и могла совпасть со старой.
+	// KEYS[1] — корзина; ARGV: ожидаемая версия ('' — любая), TTL.
+	clearCartScript = redis.NewScript(`
+local version = tonumber(redis.call('HGET', KEYS[1], '_version') or '0')
+if ARGV[1] ~= '' and tonumber(ARGV[1]) ~= version then
+	return 0
+end
+redis.call('DEL', KEYS[1])
+redis.call('HSET', KEYS[1], '_version', version + 1)
+redis.call('EXPIRE', KEYS[1], ARGV[2])
+return 1
+`)
+)
+
+func (r *cartRepository) setTTL(ctx context.Context, key string) error {
+	return r.rdb.Expire(ctx, key, cartTTL).Err()
+}
 
-	return r.setTTL(ctx, key)
+func cartTTLSeconds() int {
+	return int(cartTTL.Seconds())
 }
 
-func (r *cartRepository) RemoveItem(ctx context.Context, userID int64, productID int64) error {
-	key := r.getCartKey(userID)
-	field := strconv.Itoa(int(productID))
-	if err := r.rdb.HDel(ctx, key, field).Err(); err != nil {
-		return err
+func (r *cartRepository) AddItem(ctx context.Context, owner models.CartOwner, item models.CartItemKey, quantity int, price float64) error {
+	keys := []string{r.getCartKey(owner)}
+	return addCartItemScript.Run(ctx, r.rdb, keys, cartField(item), cartPriceField(item), quantity, price, cartTTLSeconds()).Err()
+}
+
+func (r *cartRepository) UpdateItem(ctx context.Context, owner models.CartOwner, item models.CartItemKey, quantity int, price float64) error {
+	if quantity <= 0 {
+		return r.RemoveItem(ctx, owner, item)
 	}
 
-	return r.setTTL(ctx, key)
+	keys := []string{r.getCartKey(owner)}
+	return updateCartItemScript.Run(ctx, r.rdb, keys, cartField(item), cartPriceField(item), quantity, price, cartTTLSeconds()).Err()
+}
+
+func (r *cartRepository) RemoveItem(ctx context.Context, owner models.CartOwner, item models.CartItemKey) error {
+	keys := []string{r.getCartKey(owner)}
+	return removeCartItemScript.Run(ctx, r.rdb, keys, cartField(item), cartPriceField(item), cartTTLSeconds()).Err()
 }
 
-func (r *cartRepository) GetCart(ctx context.Context, userID int64) (map[int64]int, error) {
-	key := r.getCartKey(userID)
+func (r *cartRepository) GetCart(ctx context.Context, owner models.CartOwner) (map[models.CartItemKey]models.CartLine, int64, error) {
+	key := r.getCartKey(owner)
 	data, err := r.rdb.HGetAll(ctx, key).Result()
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	cart := make(map[int64]int)
+
+	var version int64
+	cart := make(map[models.CartItemKey]models.CartLine)
+	prices := make(map[models.CartItemKey]float64)
 	for field, val := range data {
-		productID, err := strconv.ParseInt(field, 10, 64)
+		if field == cartVersionField {
+			version, err = strconv.ParseInt(val, 10, 64)
+			if err != nil {
+				return nil, 0, err
+			}
+			continue
+		}
+
+		if priceField, ok := strings.CutPrefix(field, cartPricePrefix); ok {
+			item, err := parseCartField(priceField)
+			if err != nil {
+				return nil, 0, err
+			}
+			prices[item], err = strconv.ParseFloat(val, 64)
+			if err != nil {
+				return nil, 0, err
+			}
+			continue
+		}
+
+		item, err := parseCartField(field)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		quantity, err := strconv.Atoi(val)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
-		cart[productID] = quantity
+		cart[item] = models.CartLine{Quantity: quantity}
 	}
+
+	for item, line := range cart {
+		line.Price = prices[item]
+		cart[item] = line
+	}
+
 	r.setTTL(ctx, key)
-	return cart, nil
+	return cart, version, nil
+}
+
+func (r *cartRepository) ClearCart(ctx context.Context, owner models.CartOwner) error {
+	keys := []string{r.getCartKey(owner)}
+	return clearCartScript.Run(ctx, r.rdb, keys, "", cartTTLSeconds()).Err()
 }
 
-func (r *cartRepository) ClearCart(ctx context.Context, userID int64) error {
-	key := r.getCartKey(userID)
-	return r.rdb.Del(ctx, key).Err()
+func (r *cartRepository) ClearCartIfVersion(ctx context.Context, owner models.CartOwner, version int64) (bool, error) {
+	keys := []string{r.getCartKey(owner)}
+	cleared, err := clearCartScript.Run(ctx, r.rdb, keys, version, cartTTLSeconds()).Int()
+	return cleared == 1, err
+}
+
+// mergeCartScript сливает хэш KEYS[1] (гость) в KEYS[2] (пользователь)
+// атомарно, чтобы параллельный запрос не увидел половину слияния.
+var mergeCartScript = redis.NewScript(`
+-- Цены на момент добавления не сливаются: у позиции, которая уже есть
+-- у пользователя, остаётся его цена.
+local guest = redis.call('HGETALL', KEYS[1])
+local moved = 0
+for i = 1, #guest, 2 do
+	local field, value = guest[i], guest[i + 1]
+	if field == '_version' then
+		-- версия гостевой корзины пользователю не переносится
+	elseif string.sub(field, 1, 6) == 'price:' then
+		redis.call('HSETNX', KEYS[2], field, value)
+	else
+		local quantity = tonumber(value)
+		if ARGV[1] == 'sum' then
+			redis.call('HINCRBY', KEYS[2], field, quantity)
+		elseif ARGV[1] == 'max' then
+			local current = tonumber(redis.call('HGET', KEYS[2], field) or '0')
+			if quantity > current then
+				redis.call('HSET', KEYS[2], field, quantity)
+			end
+		else
+			redis.call('HSETNX', KEYS[2], field, quantity)
+		end
+		moved = moved + 1
+	end
+end
+redis.call('DEL', KEYS[1])
+if moved > 0 then
+	redis.call('HINCRBY', KEYS[2], '_version', 1)
+	redis.call('EXPIRE', KEYS[2], ARGV[2])
+end
+return moved
+`)
+
+func (r *cartRepository) MergeGuestCart(ctx context.Context, guestID string, userID int64, strategy string) (int, error) {
+	keys := []string{
+		r.getCartKey(models.GuestCart(guestID)),
+		r.getCartKey(models.UserCart(userID)),
+	}
+	return mergeCartScript.Run(ctx, r.rdb, keys, strategy, cartTTLSeconds()).Int()
 }
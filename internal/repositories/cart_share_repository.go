@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"context"
+	"ecommerce-api/internal/models"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrCartShareNotFound = errors.New("shared cart not found or expired")
+
+// CartShareRepository хранит копии корзин, которыми поделились по ссылке.
+type CartShareRepository interface {
+	// Save сохраняет копию под token на ttl; занятый token — ошибка.
+	Save(ctx context.Context, token string, items []models.SharedCartItem, ttl time.Duration) error
+	Get(ctx context.Context, token string) ([]models.SharedCartItem, error)
+}
+
+type cartShareRepository struct {
+	rdb *redis.Client
+}
+
+func NewCartShareRepository(rdb *redis.Client) CartShareRepository {
+	return &cartShareRepository{rdb: rdb}
+}
+
+func cartShareKey(token string) string {
+	return "cart:share:" + token
+}
+
+func (r *cartShareRepository) Save(ctx context.Context, token string, items []models.SharedCartItem, ttl time.Duration) error {
+	data, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+
+	ok, err := r.rdb.SetNX(ctx, cartShareKey(token), data, ttl).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("cart share token already exists")
+	}
+	return nil
+}
+
+func (r *cartShareRepository) Get(ctx context.Context, token string) ([]models.SharedCartItem, error) {
+	data, err := r.rdb.Get(ctx, cartShareKey(token)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrCartShareNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var items []models.SharedCartItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,343 @@
+package repositories
+
+import (
+	"context"
+	"ecommerce-api/internal/models"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrCategoryHasChildren    = errors.New("category has child categories")
+	ErrCategorySlugTaken      = errors.New("category slug already exists")
+	ErrInvalidCategoryMove    = errors.New("category cannot be moved into itself or its descendant")
+	ErrParentCategoryNotFound = errors.New("parent category not found")
+)
+
+// ProductsNotFoundError — часть товаров не существует или удалена.
+type ProductsNotFoundError struct {
+	IDs []int64
+}
+
+func (e *ProductsNotFoundError) Error() string {
+	return "products not found: " + joinIDs(e.IDs)
+}
+
+type CategoryRepository interface {
+	Create(ctx context.Context, req *models.CreateCategoryRequest) (int64, error)
+	GetByID(ctx context.Context, id int64) (*models.Category, error)
+	List(ctx context.Context) ([]*models.Category, error)
+	Update(ctx context.Context, id int64, req *models.UpdateCategoryRequest) error
+	Delete(ctx context.Context, id int64) error
+	// AddProducts добавляет все товары или ни одного: если какого-то товара
+	// нет, возвращает ProductsNotFoundError.
+	AddProducts(ctx context.Context, categoryID int64, productIDs []int64) error
+	RemoveProduct(ctx context.Context, categoryID int64, productID int64) error
+	ListProducts(ctx context.Context, categoryID int64, after int64, limit int) ([]*models.Product, error)
+}
+
+type categoryRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewCategoryRepository(pool *pgxpool.Pool) CategoryRepository {
+	return &categoryRepository{pool: pool}
+}
+
+// Путь категории хранится как "/1/5/12/": id всех предков и её собственный.
+// Поддерево выбирается через path LIKE '/1/5/%'.
+func (r *categoryRepository) parentPath(ctx context.Context, tx pgx.Tx, parentID *int64) (string, error) {
+	if parentID == nil {
+		return "/", nil
+	}
+
+	var path string
+	err := tx.QueryRow(ctx, `SELECT path FROM categories WHERE id = $1 FOR SHARE`, *parentID).Scan(&path)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrParentCategoryNotFound
+		}
+		return "", err
+	}
+	return path, nil
+}
+
+func (r *categoryRepository) Create(ctx context.Context, req *models.CreateCategoryRequest) (int64, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	prefix, err := r.parentPath(ctx, tx, req.ParentID)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+		WITH new_id AS (
+			SELECT nextval(pg_get_serial_sequence('categories', 'id')) AS id
+		)
+		INSERT INTO categories (id, parent_id, name, slug, path, position)
+		SELECT id, $1, $2, $3, $4 || id || '/', $5
+		FROM new_id
+		RETURNING id`
+
+	var id int64
+	err = tx.QueryRow(ctx, query, req.ParentID, req.Name, req.Slug, prefix, req.Position).Scan(&id)
+	if err != nil {
+		return 0, mapCategoryError(err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *categoryRepository) GetByID(ctx context.Context, id int64) (*models.Category, error) {
+	c := &models.Category{}
+	err := r.pool.QueryRow(ctx, `
+		SELECT id, parent_id, name, slug, path, position, created_at, updated_at
+		FROM categories
+		WHERE id = $1`, id).
+		Scan(&c.ID, &c.ParentID, &c.Name, &c.Slug, &c.Path, &c.Position, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (r *categoryRepository) List(ctx context.Context) ([]*models.Category, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, parent_id, name, slug, path, position, created_at, updated_at
+		FROM categories
+		ORDER BY position, name, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := make([]*models.Category, 0)
+	for rows.Next() {
+		c := &models.Category{}
+		if err := rows.Scan(&c.ID, &c.ParentID, &c.Name, &c.Slug, &c.Path, &c.Position, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+func (r *categoryRepository) Update(ctx context.Context, id int64, req *models.UpdateCategoryRequest) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var oldPath string
+	err = tx.QueryRow(ctx, `SELECT path FROM categories WHERE id = $1 FOR UPDATE`, id).Scan(&oldPath)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(ctx, `
+		UPDATE categories
+		SET name = COALESCE($1, name),
+			slug = COALESCE($2, slug),
+			position = COALESCE($3, position),
+			updated_at = NOW()
+		WHERE id = $4`, req.Name, req.Slug, req.Position, id)
+	if err != nil {
+		return mapCategoryError(err)
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	if req.ParentID != nil || req.MoveToRoot {
+		var newParentID *int64
+		if !req.MoveToRoot {
+			newParentID = req.ParentID
+		}
+
+		prefix, err := r.parentPath(ctx, tx, newParentID)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(prefix, oldPath) {
+			return ErrInvalidCategoryMove
+		}
+
+		newPath := fmt.Sprintf("%s%d/", prefix, id)
+
+		_, err = tx.Exec(ctx, `UPDATE categories SET parent_id = $1 WHERE id = $2`, newParentID, id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE categories
+			SET path = $1 || substr(path, length($2) + 1),
+				updated_at = NOW()
+			WHERE path LIKE $2 || '%'`, newPath, oldPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *categoryRepository) Delete(ctx context.Context, id int64) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM categories WHERE id = $1`, id)
+	if err != nil {
+		return mapCategoryError(err)
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *categoryRepository) AddProducts(ctx context.Context, categoryID int64, productIDs []int64) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `UPDATE categories SET updated_at = NOW() WHERE id = $1`, categoryID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	// FOR SHARE не даёт удалить товар, пока он добавляется в категорию.
+	rows, err := tx.Query(ctx, `
+		SELECT id FROM products
+		WHERE id = ANY($1) AND deleted_at IS NULL
+		FOR SHARE`, productIDs)
+	if err != nil {
+		return err
+	}
+	found, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return err
+	}
+
+	if missing := missingIDs(productIDs, found); len(missing) > 0 {
+		return &ProductsNotFoundError{IDs: missing}
+	}
+
+	_, err = tx.Exec(ctx, `
+		WITH touched_products AS (
+			UPDATE products SET updated_at = NOW() WHERE id = ANY($2)
+		)
+		INSERT INTO product_categories (product_id, category_id)
+		SELECT unnest($2::bigint[]), $1
+		ON CONFLICT DO NOTHING`, categoryID, productIDs)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// missingIDs возвращает id из want, которых нет в found, без повторов.
+func missingIDs(want []int64, found []int64) []int64 {
+	present := make(map[int64]bool, len(found))
+	for _, id := range found {
+		present[id] = true
+	}
+
+	var missing []int64
+	for _, id := range want {
+		if !present[id] {
+			missing = append(missing, id)
+			present[id] = true
+		}
+	}
+	return missing
+}
+
+func (r *categoryRepository) RemoveProduct(ctx context.Context, categoryID int64, productID int64) error {
+	result, err := r.pool.Exec(ctx, `
+		WITH touched AS (
+			UPDATE categories SET updated_at = NOW() WHERE id = $1
+		), touched_products AS (
+			UPDATE products SET updated_at = NOW() WHERE id = $2
+		)
+		DELETE FROM product_categories
+		WHERE category_id = $1 AND product_id = $2`, categoryID, productID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// ListProducts возвращает товары категории вместе с товарами всех её потомков.
+func (r *categoryRepository) ListProducts(ctx context.Context, categoryID int64, after int64, limit int) ([]*models.Product, error) {
+	query := `
+		SELECT p.id, COALESCE(p.sku, ''), p.name, p.description, p.price, p.inventory, p.created_at, p.updated_at, ` + productRatingColumns + `
+		FROM products p
+		WHERE p.deleted_at IS NULL
+			AND p.id > $2
+			AND EXISTS (
+				SELECT 1
+				FROM product_categories pc
+				JOIN categories c ON c.id = pc.category_id
+				WHERE pc.product_id = p.id
+					AND c.path LIKE (SELECT path FROM categories WHERE id = $1) || '%'
+			)
+		ORDER BY p.id
+		LIMIT $3`
+
+	rows, err := r.pool.Query(ctx, query, categoryID, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := make([]*models.Product, 0)
+	for rows.Next() {
+		p := &models.Product{}
+		if err := rows.Scan(&p.ID, &p.SKU, &p.Name, &p.Description, &p.Price, &p.Inventory, &p.CreatedAt, &p.UpdatedAt, &p.Rating, &p.ReviewCount); err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
+func mapCategoryError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505":
+			return ErrCategorySlugTaken
+		case "23503":
+			return ErrCategoryHasChildren
+		}
+	}
+	return err
+}
@@ -0,0 +1,176 @@
+package repositories
+
+import (
+	"context"
+	"ecommerce-api/internal/models"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// cartRetryAfter — как долго после сбоя основного хранилища запросы
+// сразу идут в запасное, прежде чем снова попробовать основное.
+const cartRetryAfter = 10 * time.Second
+
+// fallbackVersionFlag помечает версии корзин из запасного хранилища. Версии
+// в двух хранилищах независимы, и по версии ClearCartIfVersion узнаёт,
+// из какого хранилища была прочитана корзина.
+const fallbackVersionFlag int64 = 1 << 62
+
+// failoverCartRepository держит корзины в основном хранилище (Redis), а
+// при его недоступности переключается на запасное (Postgres). Позиции,
+// попавшие в запасное хранилище во время сбоя, переносятся в основное
+// при первом чтении корзины после восстановления.
+type failoverCartRepository struct {
+	primary  CartRepository
+	fallback PostgresCartRepository
+	// failedAt — время последнего сбоя основного хранилища в UnixNano, 0 — работает.
+	failedAt atomic.Int64
+}
+
+func NewFailoverCartRepository(primary CartRepository, fallback PostgresCartRepository) CartRepository {
+	return &failoverCartRepository{primary: primary, fallback: fallback}
+}
+
+func (r *failoverCartRepository) primaryAvailable() bool {
+	failedAt := r.failedAt.Load()
+	return failedAt == 0 || time.Since(time.Unix(0, failedAt)) >= cartRetryAfter
+}
+
+// do выполняет fn на основном хранилище, а при его ошибке — на запасном.
+// Ошибкой доступности считается любая ошибка, кроме отмены самого запроса:
+// отличить сетевой сбой Redis от прочих ошибок надёжно нельзя, а повтор
+// в Postgres безопасен.
+func (r *failoverCartRepository) do(ctx context.Context, fn func(repo CartRepository) error) (usedPrimary bool, err error) {
+	if r.primaryAvailable() {
+		err := fn(r.primary)
+		if err == nil || ctx.Err() != nil {
+			if err == nil && r.failedAt.Swap(0) != 0 {
+				log.Println("cart storage recovered, using redis")
+			}
+			return true, err
+		}
+		if r.failedAt.Swap(time.Now().UnixNano()) == 0 {
+			log.Printf("cart storage unavailable, falling back to postgres: %v", err)
+		}
+	}
+	return false, fn(r.fallback)
+}
+
+func (r *failoverCartRepository) AddItem(ctx context.Context, owner models.CartOwner, item models.CartItemKey, quantity int, price float64) error {
+	_, err := r.do(ctx, func(repo CartRepository) error {
+		return repo.AddItem(ctx, owner, item, quantity, price)
+	})
+	return err
+}
+
+func (r *failoverCartRepository) UpdateItem(ctx context.Context, owner models.CartOwner, item models.CartItemKey, quantity int, price float64) error {
+	_, err := r.do(ctx, func(repo CartRepository) error {
+		return repo.UpdateItem(ctx, owner, item, quantity, price)
+	})
+	return err
+}
+
+func (r *failoverCartRepository) RemoveItem(ctx context.Context, owner models.CartOwner, item models.CartItemKey) error {
+	_, err := r.do(ctx, func(repo CartRepository) error {
+		return repo.RemoveItem(ctx, owner, item)
+	})
+	return err
+}
+
+// GetCart на основном хранилище каждый раз проверяет запасное: это лишний
+// запрос к Postgres, зато позиции, добавленные во время сбоя, не теряются.
+func (r *failoverCartRepository) GetCart(ctx context.Context, owner models.CartOwner) (map[models.CartItemKey]models.CartLine, int64, error) {
+	var cart map[models.CartItemKey]models.CartLine
+	var version int64
+	usedPrimary, err := r.do(ctx, func(repo CartRepository) error {
+		var err error
+		cart, version, err = repo.GetCart(ctx, owner)
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if !usedPrimary {
+		return cart, version | fallbackVersionFlag, nil
+	}
+
+	moved, err := r.reconcile(ctx, owner)
+	if err != nil {
+		log.Printf("cart reconcile error: %v", err)
+		return cart, version, nil
+	}
+	if !moved {
+		return cart, version, nil
+	}
+	return r.primary.GetCart(ctx, owner)
+}
+
+func (r *failoverCartRepository) ClearCart(ctx context.Context, owner models.CartOwner) error {
+	_, err := r.do(ctx, func(repo CartRepository) error {
+		return repo.ClearCart(ctx, owner)
+	})
+	return err
+}
+
+// ClearCartIfVersion очищает корзину в том хранилище, из которого её прочитали,
+// даже если основное хранилище с тех пор восстановилось: иначе заказанные
+// позиции остались бы в Postgres и вернулись бы в корзину при переносе в Redis.
+func (r *failoverCartRepository) ClearCartIfVersion(ctx context.Context, owner models.CartOwner, version int64) (bool, error) {
+	if version&fallbackVersionFlag != 0 {
+		return r.fallback.ClearCartIfVersion(ctx, owner, version&^fallbackVersionFlag)
+	}
+	return r.primary.ClearCartIfVersion(ctx, owner, version)
+}
+
+func (r *failoverCartRepository) MergeGuestCart(ctx context.Context, guestID string, userID int64, strategy string) (int, error) {
+	if r.primaryAvailable() {
+		if _, err := r.reconcile(ctx, models.GuestCart(guestID)); err != nil {
+			log.Printf("cart reconcile error: %v", err)
+		}
+	}
+
+	var moved int
+	_, err := r.do(ctx, func(repo CartRepository) error {
+		var err error
+		moved, err = repo.MergeGuestCart(ctx, guestID, userID, strategy)
+		return err
+	})
+	return moved, err
+}
+
+// reconcile переносит позиции корзины из запасного хранилища в основное.
+// Количества складываются, как при повторном добавлении товара. Позиции
+// сначала забираются из Postgres, поэтому два одновременных чтения корзины
+// не перенесут их дважды; то, что не удалось записать в Redis, возвращается
+// обратно.
+func (r *failoverCartRepository) reconcile(ctx context.Context, owner models.CartOwner) (bool, error) {
+	lines, err := r.fallback.TakeCart(ctx, owner)
+	if err != nil || len(lines) == 0 {
+		return false, err
+	}
+
+	for item, line := range lines {
+		if err := r.primary.AddItem(ctx, owner, item, line.Quantity, line.Price); err != nil {
+			r.restore(owner, lines)
+			return false, err
+		}
+		delete(lines, item)
+	}
+
+	return true, nil
+}
+
+// restore возвращает в запасное хранилище позиции, не перенесённые в основное.
+// Контекст запроса к этому моменту может быть уже отменён.
+func (r *failoverCartRepository) restore(owner models.CartOwner, lines map[models.CartItemKey]models.CartLine) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for item, line := range lines {
+		if err := r.fallback.AddItem(ctx, owner, item, line.Quantity, line.Price); err != nil {
+			log.Printf("cart reconcile: failed to restore item %d/%d for %s: %v",
+				item.ProductID, item.VariantID, pgCartID(owner), err)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package repositories
+
+import (
+	"context"
+	"ecommerce-api/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ImageRepository interface {
+	Create(ctx context.Context, image *models.ProductImage, position *int) error
+	GetByID(ctx context.Context, productID int64, imageID int64) (*models.ProductImage, error)
+	ListByProducts(ctx context.Context, productIDs []int64) ([]*models.ProductImage, error)
+	Update(ctx context.Context, productID int64, imageID int64, req *models.UpdateProductImageRequest) (*models.ProductImage, error)
+	Delete(ctx context.Context, productID int64, imageID int64) error
+}
+
+// Изменения картинок обновляют products.updated_at, чтобы кэши каталога
+// видели их как изменение товара.
+type imageRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewImageRepository(pool *pgxpool.Pool) ImageRepository {
+	return &imageRepository{pool: pool}
+}
+
+// Create без явной позиции ставит картинку в конец списка товара.
+func (r *imageRepository) Create(ctx context.Context, image *models.ProductImage, position *int) error {
+	query := `
+		WITH touched AS (
+			UPDATE products SET updated_at = NOW() WHERE id = $1
+		)
+		INSERT INTO product_images (product_id, file_key, thumbnail_key, content_type, width, height, alt_text, position)
+		VALUES ($1, $2, $3, $4, $5, $6, $7,
+			COALESCE($8, (SELECT COALESCE(MAX(position) + 1, 0) FROM product_images WHERE product_id = $1)))
+		RETURNING id, position, created_at`
+
+	return r.pool.QueryRow(ctx, query,
+		image.ProductID, image.FileKey, image.ThumbnailKey, image.ContentType,
+		image.Width, image.Height, image.AltText, position,
+	).Scan(&image.ID, &image.Position, &image.CreatedAt)
+}
+
+func (r *imageRepository) GetByID(ctx context.Context, productID int64, imageID int64) (*models.ProductImage, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, product_id, file_key, thumbnail_key, content_type, width, height, alt_text, position, created_at
+		FROM product_images
+		WHERE id = $1 AND product_id = $2`, imageID, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := scanImages(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(images) == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return images[0], nil
+}
+
+func (r *imageRepository) ListByProducts(ctx context.Context, productIDs []int64) ([]*models.ProductImage, error) {
+	if len(productIDs) == 0 {
+		return []*models.ProductImage{}, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, product_id, file_key, thumbnail_key, content_type, width, height, alt_text, position, created_at
+		FROM product_images
+		WHERE product_id = ANY($1)
+		ORDER BY product_id, position, id`, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	return scanImages(rows)
+}
+
+func (r *imageRepository) Update(ctx context.Context, productID int64, imageID int64, req *models.UpdateProductImageRequest) (*models.ProductImage, error) {
+	rows, err := r.pool.Query(ctx, `
+		WITH touched AS (
+			UPDATE products SET updated_at = NOW() WHERE id = $4
+		)
+		UPDATE product_images
+		SET alt_text = COALESCE($1, alt_text),
+			position = COALESCE($2, position)
+		WHERE id = $3 AND product_id = $4
+		RETURNING id, product_id, file_key, thumbnail_key, content_type, width, height, alt_text, position, created_at`,
+		req.AltText, req.Position, imageID, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := scanImages(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(images) == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return images[0], nil
+}
+
+func (r *imageRepository) Delete(ctx context.Context, productID int64, imageID int64) error {
+	result, err := r.pool.Exec(ctx, `
+		WITH touched AS (
+			UPDATE products SET updated_at = NOW() WHERE id = $2
+		)
+		DELETE FROM product_images
+		WHERE id = $1 AND product_id = $2`, imageID, productID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func scanImages(rows pgx.Rows) ([]*models.ProductImage, error) {
+	defer rows.Close()
+
+	images := make([]*models.ProductImage, 0)
+	for rows.Next() {
+		img := &models.ProductImage{}
+		if err := rows.Scan(
+			&img.ID, &img.ProductID, &img.FileKey, &img.ThumbnailKey, &img.ContentType,
+			&img.Width, &img.Height, &img.AltText, &img.Position, &img.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"ecommerce-api/internal/models"
+	"errors"
 	"fmt"
 
 	"github.com/jackc/pgx/v5"
@@ -13,9 +14,19 @@ type OrderRepository interface {
 	CreateOrder(ctx context.Context, tx pgx.Tx, order *models.Order, items []models.OrderItem) error
 	GetOrderByID(ctx context.Context, orderID int64, userID int64) (*models.OrderResponse, error)
 	ListOrders(ctx context.Context, userID int64) ([]*models.OrderResponse, error)
-	UpdateOrderStatus(ctx context.Context, orderID int64, newStatus string) error
+	// UpdateOrderStatus переводит заказ в newStatus, только если его текущий
+	// статус входит в allowedFrom, и в той же транзакции пишет переход в историю.
+	// Возвращает статус до перехода; если переход не разрешён — текущий статус
+	// и ErrOrderStatusConflict.
+	UpdateOrderStatus(ctx context.Context, orderID int64, allowedFrom []models.OrderStatus, newStatus models.OrderStatus, change models.OrderStatusChange) (models.OrderStatus, error)
+	// ListStatusHistory возвращает переходы заказа пользователя по порядку.
+	ListStatusHistory(ctx context.Context, orderID int64, userID int64) ([]models.OrderStatusHistoryEntry, error)
+	ListUnexported(ctx context.Context, limit int) ([]*models.ExportOrder, error)
+	MarkExported(ctx context.Context, orderIDs []int64) error
 }
 
+var ErrOrderStatusConflict = errors.New("order status does not allow this transition")
+
 type orderRepository struct {
 	pool *pgxpool.Pool
 }
@@ -36,30 +47,45 @@ func (r *orderRepository) CreateOrder(ctx context.Context, tx pgx.Tx, order *mod
 	}
 
 	queryItem := `
-		INSERT INTO order_items (order_id, product_id, quantity, price_at_purchase)
-		VALUES ($1, $2, $3, $4)`
+		INSERT INTO order_items (order_id, product_id, variant_id, quantity, price_at_purchase)
+		VALUES ($1, $2, $3, $4, $5)`
 
 	for i := range items {
 		item := &items[i]
 		item.OrderID = order.ID
-		_, err := tx.Exec(ctx, queryItem, item.OrderID, item.ProductID, item.Quantity, item.PriceAtPurchase)
+		_, err := tx.Exec(ctx, queryItem, item.OrderID, item.ProductID, item.VariantID, item.Quantity, item.PriceAtPurchase)
 		if err != nil {
 			return err
 		}
 	}
 
-	return nil
+	return insertStatusHistory(ctx, tx, order.ID, nil, order.Status, models.OrderStatusChange{
+		Source:  models.OrderSourceCustomer,
+		ActorID: &order.UserID,
+	})
+}
+
+func insertStatusHistory(ctx context.Context, tx pgx.Tx, orderID int64, from *models.OrderStatus, to models.OrderStatus, change models.OrderStatusChange) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO order_status_history (order_id, from_status, to_status, source, actor_id, comment)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		orderID, from, to, change.Source, change.ActorID, change.Comment)
+	return err
 }
 
+// Товары намеренно не фильтруются по deleted_at, чтобы старые заказы
+// отображались и после удаления товара из каталога.
 func (r *orderRepository) GetOrderByID(ctx context.Context, orderID int64, userID int64) (*models.OrderResponse, error) {
 	query := `
 		SELECT 
 			o.id, o.user_id, o.status, o.total_amount, o.created_at, o.updated_at,
-			oi.product_id, oi.quantity, oi.price_at_purchase,
-			p.name, p.description, p.price
+			oi.product_id, oi.variant_id, oi.quantity, oi.price_at_purchase,
+			p.name, p.description, p.price,
+			v.sku, v.options
 		FROM orders o
 		JOIN order_items oi ON o.id = oi.order_id
 		JOIN products p ON oi.product_id = p.id
+		LEFT JOIN product_variants v ON oi.variant_id = v.id
 		WHERE o.id = $1 AND o.user_id = $2
 		ORDER BY oi.id`
 
@@ -76,15 +102,20 @@ func (r *orderRepository) GetOrderByID(ctx context.Context, orderID int64, userI
 		var item models.OrderResponseItem
 		var order models.Order
 		var dummyPrice float64
+		var sku *string
 
 		err := rows.Scan(
 			&order.ID, &order.UserID, &order.Status, &order.TotalAmount, &order.CreatedAt, &order.UpdatedAt,
-			&item.ProductID, &item.Quantity, &item.Price,
+			&item.ProductID, &item.VariantID, &item.Quantity, &item.Price,
 			&item.Name, &item.Description, &dummyPrice,
+			&sku, &item.Options,
 		)
 		if err != nil {
 			return nil, err
 		}
+		if sku != nil {
+			item.SKU = *sku
+		}
 
 		if response == nil {
 			response = &models.OrderResponse{
@@ -140,22 +171,137 @@ func (r *orderRepository) ListOrders(ctx context.Context, userID int64) ([]*mode
 	return orders, rows.Err()
 }
 
-func (r *orderRepository) UpdateOrderStatus(ctx context.Context, orderID int64, newStatus string) error {
-	query := `
+func (r *orderRepository) UpdateOrderStatus(ctx context.Context, orderID int64, allowedFrom []models.OrderStatus, newStatus models.OrderStatus, change models.OrderStatusChange) (models.OrderStatus, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	// FOR UPDATE, чтобы два одновременных вебхука не перевели заказ
+	// из одного и того же статуса дважды.
+	var current models.OrderStatus
+	err = tx.QueryRow(ctx, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, orderID).Scan(&current)
+	if err != nil {
+		return "", err
+	}
+
+	allowed := false
+	for _, s := range allowedFrom {
+		if s == current {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return current, ErrOrderStatusConflict
+	}
+
+	_, err = tx.Exec(ctx, `
 		UPDATE orders
-		SET status = $1,
+		SET status = $2,
 			updated_at = NOW()
-		WHERE id = $2`
+		WHERE id = $1`, orderID, newStatus)
+	if err != nil {
+		return "", fmt.Errorf("failed to update order status: %w", err)
+	}
+
+	if err := insertStatusHistory(ctx, tx, orderID, &current, newStatus, change); err != nil {
+		return "", fmt.Errorf("failed to record order status history: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+	return current, nil
+}
 
-	result, err := r.pool.Exec(ctx, query, newStatus, orderID)
+func (r *orderRepository) ListStatusHistory(ctx context.Context, orderID int64, userID int64) ([]models.OrderStatusHistoryEntry, error) {
+	var exists bool
+	err := r.pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM orders WHERE id = $1 AND user_id = $2)`, orderID, userID).Scan(&exists)
 	if err != nil {
-		return fmt.Errorf("failed to update order status: %w", err)
+		return nil, err
+	}
+	if !exists {
+		return nil, pgx.ErrNoRows
 	}
 
-	rowsAffected := result.RowsAffected()
-	if rowsAffected == 0 {
-		return fmt.Errorf("order %d not found or already in final state", orderID)
+	rows, err := r.pool.Query(ctx, `
+		SELECT from_status, to_status, source, comment, created_at
+		FROM order_status_history
+		WHERE order_id = $1
+		ORDER BY id`, orderID)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	return nil
+	history := make([]models.OrderStatusHistoryEntry, 0)
+	for rows.Next() {
+		var entry models.OrderStatusHistoryEntry
+		if err := rows.Scan(&entry.FromStatus, &entry.ToStatus, &entry.Source, &entry.Comment, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, entry)
+	}
+	return history, rows.Err()
+}
+
+// ListUnexported возвращает заказы, которые ещё не забрала 1С, вместе с позициями.
+func (r *orderRepository) ListUnexported(ctx context.Context, limit int) ([]*models.ExportOrder, error) {
+	query := `
+		SELECT
+			o.id, o.user_id, u.email, o.status, o.total_amount, o.created_at,
+			oi.product_id, COALESCE(p.external_id, ''), COALESCE(p.sku, ''), p.name, oi.quantity, oi.price_at_purchase
+		FROM (
+			SELECT * FROM orders
+			WHERE exported_at IS NULL
+			ORDER BY id
+			LIMIT $1
+		) o
+		JOIN users u ON u.id = o.user_id
+		JOIN order_items oi ON oi.order_id = o.id
+		JOIN products p ON p.id = oi.product_id
+		ORDER BY o.id, oi.id`
+
+	rows, err := r.pool.Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := make([]*models.ExportOrder, 0)
+	var current *models.ExportOrder
+	for rows.Next() {
+		var o models.ExportOrder
+		var item models.ExportOrderItem
+		err := rows.Scan(
+			&o.ID, &o.UserID, &o.Email, &o.Status, &o.TotalAmount, &o.CreatedAt,
+			&item.ProductID, &item.ExternalID, &item.SKU, &item.Name, &item.Quantity, &item.PriceAtPurchase,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if current == nil || current.ID != o.ID {
+			current = &o
+			orders = append(orders, current)
+		}
+		current.Items = append(current.Items, item)
+	}
+
+	return orders, rows.Err()
+}
+
+func (r *orderRepository) MarkExported(ctx context.Context, orderIDs []int64) error {
+	if len(orderIDs) == 0 {
+		return nil
+	}
+
+	_, err := r.pool.Exec(ctx, `
+		UPDATE orders
+		SET exported_at = NOW()
+		WHERE id = ANY($1) AND exported_at IS NULL`, orderIDs)
+	return err
 }
@@ -0,0 +1,289 @@
+package repositories
+
+import (
+	"context"
+	"ecommerce-api/internal/models"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// pgCartRepository хранит корзины в Postgres с теми же правилами, что и
+// Redis: корзина живёт cartTTL с последнего обращения, а цена снимка
+// при слиянии берётся из корзины пользователя.
+type pgCartRepository struct {
+	pool *pgxpool.Pool
+}
+
+// PostgresCartRepository — корзины в Postgres; умеет ещё забрать корзину
+// целиком, что нужно failoverCartRepository для переноса в Redis.
+type PostgresCartRepository interface {
+	CartRepository
+	// TakeCart одним запросом удаляет позиции корзины и возвращает их:
+	// при одновременных вызовах каждая позиция достаётся только одному.
+	TakeCart(ctx context.Context, owner models.CartOwner) (map[models.CartItemKey]models.CartLine, error)
+}
+
+func NewPostgresCartRepository(pool *pgxpool.Pool) PostgresCartRepository {
+	return &pgCartRepository{pool: pool}
+}
+
+func pgCartID(owner models.CartOwner) string {
+	if owner.IsGuest() {
+		return "guest:" + owner.GuestID
+	}
+	return "user:" + strconv.FormatInt(owner.UserID, 10)
+}
+
+func pgCartUserID(owner models.CartOwner) *int64 {
+	if owner.IsGuest() {
+		return nil
+	}
+	return &owner.UserID
+}
+
+// touch продлевает корзину и увеличивает её версию; просроченная корзина
+// сначала удаляется, чтобы в неё не добавлялось к давно забытым позициям.
+func (r *pgCartRepository) touch(ctx context.Context, tx pgx.Tx, owner models.CartOwner) error {
+	cartID := pgCartID(owner)
+
+	_, err := tx.Exec(ctx, `
+		DELETE FROM carts
+		WHERE id = $1 AND updated_at < NOW() - $2::interval`, cartID, cartTTL)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO carts (id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (id) DO UPDATE
+		SET updated_at = NOW(),
+			version = carts.version + 1`, cartID, pgCartUserID(owner))
+	return err
+}
+
+func (r *pgCartRepository) AddItem(ctx context.Context, owner models.CartOwner, item models.CartItemKey, quantity int, price float64) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := r.touch(ctx, tx, owner); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO cart_items (cart_id, product_id, variant_id, quantity, price)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (cart_id, product_id, variant_id) DO UPDATE
+		SET quantity = cart_items.quantity + EXCLUDED.quantity`,
+		pgCartID(owner), item.ProductID, item.VariantID, quantity, price)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *pgCartRepository) UpdateItem(ctx context.Context, owner models.CartOwner, item models.CartItemKey, quantity int, price float64) error {
+	if quantity <= 0 {
+		return r.RemoveItem(ctx, owner, item)
+	}
+
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := r.touch(ctx, tx, owner); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO cart_items (cart_id, product_id, variant_id, quantity, price)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (cart_id, product_id, variant_id) DO UPDATE
+		SET quantity = EXCLUDED.quantity`,
+		pgCartID(owner), item.ProductID, item.VariantID, quantity, price)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *pgCartRepository) RemoveItem(ctx context.Context, owner models.CartOwner, item models.CartItemKey) error {
+	_, err := r.pool.Exec(ctx, `
+		WITH removed AS (
+			DELETE FROM cart_items
+			WHERE cart_id = $1 AND product_id = $2 AND variant_id = $3
+			RETURNING cart_id
+		)
+		UPDATE carts
+		SET updated_at = NOW(),
+			version = version + 1
+		WHERE id IN (SELECT cart_id FROM removed)`,
+		pgCartID(owner), item.ProductID, item.VariantID)
+	return err
+}
+
+func (r *pgCartRepository) GetCart(ctx context.Context, owner models.CartOwner) (map[models.CartItemKey]models.CartLine, int64, error) {
+	cartID := pgCartID(owner)
+
+	rows, err := r.pool.Query(ctx, `
+		WITH cart AS (
+			UPDATE carts SET updated_at = NOW()
+			WHERE id = $1 AND updated_at >= NOW() - $2::interval
+			RETURNING id, version
+		)
+		SELECT cart.version, ci.product_id, ci.variant_id, ci.quantity, ci.price
+		FROM cart
+		LEFT JOIN cart_items ci ON ci.cart_id = cart.id`, cartID, cartTTL)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var version int64
+	cart := make(map[models.CartItemKey]models.CartLine)
+	for rows.Next() {
+		var productID, variantID *int64
+		var quantity *int
+		var price *float64
+		if err := rows.Scan(&version, &productID, &variantID, &quantity, &price); err != nil {
+			return nil, 0, err
+		}
+		// Пустая корзина даёт одну строку без позиции — только с версией.
+		if productID == nil {
+			continue
+		}
+		item := models.CartItemKey{ProductID: *productID, VariantID: *variantID}
+		cart[item] = models.CartLine{Quantity: *quantity, Price: *price}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return cart, version, nil
+}
+
+func (r *pgCartRepository) TakeCart(ctx context.Context, owner models.CartOwner) (map[models.CartItemKey]models.CartLine, error) {
+	rows, err := r.pool.Query(ctx, `
+		WITH cart AS (
+			UPDATE carts
+			SET updated_at = NOW(),
+				version = version + 1
+			WHERE id = $1 AND updated_at >= NOW() - $2::interval
+			RETURNING id
+		)
+		DELETE FROM cart_items ci
+		USING cart
+		WHERE ci.cart_id = cart.id
+		RETURNING ci.product_id, ci.variant_id, ci.quantity, ci.price`, pgCartID(owner), cartTTL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cart := make(map[models.CartItemKey]models.CartLine)
+	for rows.Next() {
+		var item models.CartItemKey
+		var line models.CartLine
+		if err := rows.Scan(&item.ProductID, &item.VariantID, &line.Quantity, &line.Price); err != nil {
+			return nil, err
+		}
+		cart[item] = line
+	}
+	return cart, rows.Err()
+}
+
+// Очистка удаляет позиции, но не саму корзину: версия должна продолжать
+// расти, иначе после очистки она могла бы совпасть со старой.
+func (r *pgCartRepository) ClearCart(ctx context.Context, owner models.CartOwner) error {
+	_, err := r.pool.Exec(ctx, `
+		WITH touched AS (
+			UPDATE carts
+			SET updated_at = NOW(),
+				version = version + 1
+			WHERE id = $1
+		)
+		DELETE FROM cart_items WHERE cart_id = $1`, pgCartID(owner))
+	return err
+}
+
+func (r *pgCartRepository) ClearCartIfVersion(ctx context.Context, owner models.CartOwner, version int64) (bool, error) {
+	cartID := pgCartID(owner)
+
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE carts
+		SET updated_at = NOW(),
+			version = version + 1
+		WHERE id = $1 AND version = $2`, cartID, version)
+	if err != nil {
+		return false, err
+	}
+	if result.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM cart_items WHERE cart_id = $1`, cartID); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *pgCartRepository) MergeGuestCart(ctx context.Context, guestID string, userID int64, strategy string) (int, error) {
+	guest := models.GuestCart(guestID)
+	user := models.UserCart(userID)
+
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := r.touch(ctx, tx, user); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(ctx, `
+		INSERT INTO cart_items (cart_id, product_id, variant_id, quantity, price)
+		SELECT $1, ci.product_id, ci.variant_id, ci.quantity, ci.price
+		FROM cart_items ci
+		JOIN carts c ON c.id = ci.cart_id
+		WHERE ci.cart_id = $2 AND c.updated_at >= NOW() - $4::interval
+		ON CONFLICT (cart_id, product_id, variant_id) DO UPDATE
+		SET quantity = CASE $3::text
+			WHEN 'sum' THEN cart_items.quantity + EXCLUDED.quantity
+			WHEN 'max' THEN GREATEST(cart_items.quantity, EXCLUDED.quantity)
+			ELSE cart_items.quantity
+		END`,
+		pgCartID(user), pgCartID(guest), strategy, cartTTL)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM carts WHERE id = $1`, pgCartID(guest)); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return int(result.RowsAffected()), nil
+}
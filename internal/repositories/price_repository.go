@@ -0,0 +1,162 @@
+package repositories
+
+import (
+	"context"
+	"ecommerce-api/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PriceRepository interface {
+	ListHistory(ctx context.Context, productID int64, from *time.Time, limit int) ([]*models.PriceHistoryEntry, error)
+	LowestSince(ctx context.Context, productID int64, since time.Time) (*float64, error)
+	Schedule(ctx context.Context, sp *models.ScheduledPrice) error
+	ListScheduled(ctx context.Context, productID int64) ([]*models.ScheduledPrice, error)
+	CancelScheduled(ctx context.Context, productID int64, id int64) error
+	ApplyDue(ctx context.Context) ([]int64, error)
+}
+
+// Историю цен пишет триггер на products, здесь она только читается.
+type priceRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPriceRepository(pool *pgxpool.Pool) PriceRepository {
+	return &priceRepository{pool: pool}
+}
+
+func (r *priceRepository) ListHistory(ctx context.Context, productID int64, from *time.Time, limit int) ([]*models.PriceHistoryEntry, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT price, changed_at
+		FROM product_prices
+		WHERE product_id = $1 AND ($2::timestamptz IS NULL OR changed_at >= $2)
+		ORDER BY changed_at DESC, id DESC
+		LIMIT $3`, productID, from, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]*models.PriceHistoryEntry, 0)
+	for rows.Next() {
+		e := &models.PriceHistoryEntry{}
+		if err := rows.Scan(&e.Price, &e.ChangedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// LowestSince учитывает и цену, которая уже действовала на момент since.
+func (r *priceRepository) LowestSince(ctx context.Context, productID int64, since time.Time) (*float64, error) {
+	var lowest *float64
+	err := r.pool.QueryRow(ctx, `
+		SELECT MIN(price)
+		FROM product_prices
+		WHERE product_id = $1
+			AND (changed_at >= $2 OR id = (
+				SELECT id FROM product_prices
+				WHERE product_id = $1 AND changed_at < $2
+				ORDER BY changed_at DESC, id DESC
+				LIMIT 1
+			))`, productID, since).Scan(&lowest)
+	if err != nil {
+		return nil, err
+	}
+	return lowest, nil
+}
+
+func (r *priceRepository) Schedule(ctx context.Context, sp *models.ScheduledPrice) error {
+	return r.pool.QueryRow(ctx, `
+		INSERT INTO scheduled_prices (product_id, price, apply_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, status, created_at`,
+		sp.ProductID, sp.Price, sp.ApplyAt,
+	).Scan(&sp.ID, &sp.Status, &sp.CreatedAt)
+}
+
+func (r *priceRepository) ListScheduled(ctx context.Context, productID int64) ([]*models.ScheduledPrice, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, product_id, price, apply_at, status, applied_at, created_at
+		FROM scheduled_prices
+		WHERE product_id = $1
+		ORDER BY apply_at DESC, id DESC`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scheduled := make([]*models.ScheduledPrice, 0)
+	for rows.Next() {
+		sp := &models.ScheduledPrice{}
+		if err := rows.Scan(&sp.ID, &sp.ProductID, &sp.Price, &sp.ApplyAt, &sp.Status, &sp.AppliedAt, &sp.CreatedAt); err != nil {
+			return nil, err
+		}
+		scheduled = append(scheduled, sp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return scheduled, nil
+}
+
+// CancelScheduled отменяет только ещё не применённое изменение.
+func (r *priceRepository) CancelScheduled(ctx context.Context, productID int64, id int64) error {
+	result, err := r.pool.Exec(ctx, `
+		UPDATE scheduled_prices
+		SET status = 'canceled'
+		WHERE id = $1 AND product_id = $2 AND status = 'pending'`, id, productID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// ApplyDue применяет наступившие изменения цен одним запросом. SKIP LOCKED
+// позволяет запускать планировщик на нескольких инстансах. Если для товара
+// накопилось несколько изменений, остаётся самое позднее. Возвращает id
+// товаров, у которых поменялась цена.
+func (r *priceRepository) ApplyDue(ctx context.Context) ([]int64, error) {
+	rows, err := r.pool.Query(ctx, `
+		WITH due AS (
+			SELECT id
+			FROM scheduled_prices
+			WHERE status = 'pending' AND apply_at <= NOW()
+			FOR UPDATE SKIP LOCKED
+		), applied AS (
+			UPDATE scheduled_prices s
+			SET status = 'applied',
+				applied_at = NOW()
+			FROM due
+			WHERE s.id = due.id
+			RETURNING s.id, s.product_id, s.price, s.apply_at
+		), latest AS (
+			SELECT DISTINCT ON (product_id) product_id, price
+			FROM applied
+			ORDER BY product_id, apply_at DESC, id DESC
+		)
+		UPDATE products p
+		SET price = latest.price,
+			updated_at = NOW()
+		FROM latest
+		WHERE p.id = latest.product_id AND p.deleted_at IS NULL
+		RETURNING p.id`)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[int64])
+}
@@ -3,14 +3,166 @@ package repositories
 import (
 	"context"
 	"ecommerce-api/internal/models"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type ProductRepository interface {
 	Create(ctx context.Context, req *models.CreateProductRequest) (int64, error)
-	List(ctx context.Context) ([]*models.Product, error)
+	List(ctx context.Context, params *models.ProductListParams) (*models.ProductListResponse, error)
+	GetByID(ctx context.Context, id int64) (*models.Product, error)
 	GetByIDs(ctx context.Context, ids []int64) ([]*models.Product, error)
+	Update(ctx context.Context, id int64, req *models.UpdateProductRequest) (*models.Product, error)
+	Delete(ctx context.Context, id int64) error
+	Search(ctx context.Context, query string, limit int) ([]*models.ProductSearchResult, error)
+	UpsertBySKU(ctx context.Context, tx pgx.Tx, reqs []*models.ImportProduct) ([]string, error)
+	ForEach(ctx context.Context, fn func(p *models.Product) error) error
+	CatalogVersion(ctx context.Context) (string, error)
+	UpsertExternal(ctx context.Context, tx pgx.Tx, products []models.ExternalProduct) error
+	UpdateExternalOffers(ctx context.Context, tx pgx.Tx, offers []models.ExternalOffer) (int, error)
+	SetAttributes(ctx context.Context, id int64, attributes map[string]any) error
+	FacetCounts(ctx context.Context, params *models.ProductListParams, codes []string, skipAttr string) (map[string][]models.FacetValue, error)
+	// InvalidateCache вызывается после изменений товаров в обход репозитория
+	// (транзакции заказов, импорт, планировщик цен). Без ids сбрасывает всё.
+	InvalidateCache(ctx context.Context, ids ...int64)
+}
+
+const (
+	defaultProductPageSize  = 20
+	defaultSearchResultSize = 20
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// productRatingColumns считает средний рейтинг из счётчиков, которые
+// ReviewRepository поддерживает при модерации отзывов.
+const productRatingColumns = "COALESCE(ROUND(rating_sum::numeric / NULLIF(rating_count, 0), 2), 0), rating_count"
+
+var productSortColumns = map[string]string{
+	"price":      "price",
+	"created_at": "created_at",
+	"name":       "name",
+}
+
+var productSortColumnTypes = map[string]string{
+	"price":      "numeric",
+	"created_at": "timestamptz",
+	"name":       "text",
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// productCursor — позиция последнего отданного товара: значение колонки
+// сортировки и id для разрешения одинаковых значений. Сортировка и её
+// направление запоминаются, чтобы курсор нельзя было применить к другому порядку.
+type productCursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v,omitempty"`
+	ID    int64  `json:"id"`
+}
+
+func encodeProductCursor(sortColumn string, desc bool, p *models.Product) string {
+	cur := productCursor{Sort: sortColumn, Desc: desc, ID: p.ID}
+	switch sortColumn {
+	case "price":
+		cur.Value = strconv.FormatFloat(p.Price, 'f', -1, 64)
+	case "created_at":
+		cur.Value = p.CreatedAt.Format(time.RFC3339Nano)
+	case "name":
+		cur.Value = p.Name
+	}
+
+	data, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeProductCursor(s string, sortColumn string, desc bool) (*productCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cur productCursor
+	if err := json.Unmarshal(data, &cur); err != nil || cur.Sort != sortColumn || cur.Desc != desc {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cur, nil
+}
+
+// productFilterConditions собирает условия WHERE для списка товаров и фасетов.
+// Фильтры по атрибуту skipAttr пропускаются: фасет атрибута считается без
+// его собственного фильтра, чтобы можно было выбрать несколько значений.
+func productFilterConditions(params *models.ProductListParams, skipAttr string, addArg func(v any) string) []string {
+	conditions := []string{"deleted_at IS NULL"}
+
+	if params.MinPrice != nil {
+		conditions = append(conditions, "price >= "+addArg(*params.MinPrice))
+	}
+	if params.MaxPrice != nil {
+		conditions = append(conditions, "price <= "+addArg(*params.MaxPrice))
+	}
+	if params.InStock != nil {
+		if *params.InStock {
+			conditions = append(conditions, "inventory > 0")
+		} else {
+			conditions = append(conditions, "inventory = 0")
+		}
+	}
+	if q := strings.TrimSpace(params.Query); q != "" {
+		conditions = append(conditions, "name ILIKE "+addArg("%"+likeEscaper.Replace(q)+"%"))
+	}
+
+	for _, f := range params.Attributes {
+		if f.Code == skipAttr {
+			continue
+		}
+		conditions = append(conditions, attributeFilterCondition(f, addArg))
+	}
+
+	return conditions
+}
+
+var attributeRangeOps = map[string]string{
+	"gt":  ">",
+	"gte": ">=",
+	"lt":  "<",
+	"lte": "<=",
+}
+
+var attributeValueCasts = map[string]string{
+	models.AttributeString:  "text",
+	models.AttributeEnum:    "text",
+	models.AttributeNumber:  "numeric",
+	models.AttributeBoolean: "boolean",
+}
+
+// attributeFilterCondition проверяет равенство через @>, чтобы работал
+// GIN-индекс; несколько значений одного атрибута объединяются через OR.
+// Значения уже проверены сервисом на соответствие типу атрибута.
+func attributeFilterCondition(f models.AttributeFilter, addArg func(v any) string) string {
+	code := addArg(f.Code) + "::text"
+
+	if op, ok := attributeRangeOps[f.Op]; ok {
+		return fmt.Sprintf("CASE WHEN jsonb_typeof(attributes->%s) = 'number' THEN (attributes->>%s)::numeric END %s %s::numeric",
+			code, code, op, addArg(f.Values[0]))
+	}
+
+	alternatives := make([]string, len(f.Values))
+	for i, v := range f.Values {
+		alternatives[i] = fmt.Sprintf("attributes @> jsonb_build_object(%s, %s::%s)", code, addArg(v), attributeValueCasts[f.Type])
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")"
 }
 
 type productRepository struct {
@@ -20,37 +172,122 @@ type productRepository struct {
 func (r *productRepository) Create(ctx context.Context, req *models.CreateProductRequest) (int64, error) {
 	var id int64
 	err := r.pool.QueryRow(ctx, `
-        INSERT INTO products (name, description, price, inventory)
-        VALUES ($1, $2, $3, $4)
+        INSERT INTO products (sku, name, description, price, inventory)
+        VALUES (NULLIF($1, ''), $2, $3, $4, $5)
         RETURNING id`,
-		req.Name, req.Description, req.Price, req.Inventory).Scan(&id)
+		req.SKU, req.Name, req.Description, req.Price, req.Inventory).Scan(&id)
 	if err != nil {
 		return 0, err
 	}
 	return id, nil
 }
 
-func (r *productRepository) List(ctx context.Context) ([]*models.Product, error) {
-	rows, err := r.pool.Query(ctx, `
-		SELECT id, name, description, price, inventory, created_at, updated_at
+func (r *productRepository) List(ctx context.Context, params *models.ProductListParams) (*models.ProductListResponse, error) {
+	sortColumn, ok := productSortColumns[params.Sort]
+	if !ok {
+		sortColumn = "id"
+	}
+
+	desc := params.Order == "desc"
+
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultProductPageSize
+	}
+
+	args := make([]any, 0)
+	addArg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conditions := productFilterConditions(params, "", addArg)
+
+	var total int64
+	countQuery := "SELECT COUNT(*) FROM products WHERE " + strings.Join(conditions, " AND ")
+	if err := r.pool.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	if params.After != "" {
+		cur, err := decodeProductCursor(params.After, sortColumn, desc)
+		if err != nil {
+			return nil, err
+		}
+
+		op := ">"
+		if desc {
+			op = "<"
+		}
+
+		if sortColumn == "id" {
+			conditions = append(conditions, fmt.Sprintf("id %s %s", op, addArg(cur.ID)))
+		} else {
+			value := addArg(cur.Value) + "::" + productSortColumnTypes[sortColumn]
+			conditions = append(conditions, fmt.Sprintf("(%s, id) %s (%s, %s)", sortColumn, op, value, addArg(cur.ID)))
+		}
+	}
+
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+
+	orderBy := "id " + direction
+	if sortColumn != "id" {
+		orderBy = sortColumn + " " + direction + ", " + orderBy
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, COALESCE(sku, ''), name, description, price, inventory, created_at, updated_at, %s, attributes
 		FROM products
-		ORDER BY id`)
+		WHERE %s
+		ORDER BY %s
+		LIMIT %s`, productRatingColumns, strings.Join(conditions, " AND "), orderBy, addArg(limit+1))
+
+	rows, err := r.pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	products := make([]*models.Product, 0)
+	products := make([]*models.Product, 0, limit)
 	for rows.Next() {
 		p := &models.Product{}
-		rows.Scan(&p.ID, &p.Name, &p.Description, &p.Price, &p.Inventory, &p.CreatedAt, &p.UpdatedAt)
+		if err := rows.Scan(&p.ID, &p.SKU, &p.Name, &p.Description, &p.Price, &p.Inventory, &p.CreatedAt, &p.UpdatedAt, &p.Rating, &p.ReviewCount, &p.Attributes); err != nil {
+			return nil, err
+		}
 		products = append(products, p)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return products, nil
+	response := &models.ProductListResponse{
+		Items: products,
+		Total: total,
+	}
+
+	if len(products) > limit {
+		response.Items = products[:limit]
+		response.NextCursor = encodeProductCursor(sortColumn, desc, products[limit-1])
+	}
+
+	return response, nil
+}
+
+func (r *productRepository) GetByID(ctx context.Context, id int64) (*models.Product, error) {
+	p := &models.Product{}
+	err := r.pool.QueryRow(ctx, `
+		SELECT id, COALESCE(sku, ''), name, description, price, inventory, created_at, updated_at, `+productRatingColumns+`,
+			ARRAY(SELECT category_id FROM product_categories WHERE product_id = p.id ORDER BY category_id), attributes
+		FROM products p
+		WHERE id = $1 AND deleted_at IS NULL`, id).
+		Scan(&p.ID, &p.SKU, &p.Name, &p.Description, &p.Price, &p.Inventory, &p.CreatedAt, &p.UpdatedAt, &p.Rating, &p.ReviewCount, &p.CategoryIDs, &p.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
 }
 
 func (r *productRepository) GetByIDs(ctx context.Context, ids []int64) ([]*models.Product, error) {
@@ -59,9 +296,9 @@ func (r *productRepository) GetByIDs(ctx context.Context, ids []int64) ([]*model
 	}
 
 	query := `
-		SELECT id, name, description, price, inventory, created_at, updated_at
+		SELECT id, COALESCE(sku, ''), name, description, price, inventory, created_at, updated_at, ` + productRatingColumns + `
 		FROM products
-		WHERE id = ANY($1)
+		WHERE id = ANY($1) AND deleted_at IS NULL
 		ORDER BY id`
 
 	rows, err := r.pool.Query(ctx, query, ids)
@@ -73,7 +310,7 @@ func (r *productRepository) GetByIDs(ctx context.Context, ids []int64) ([]*model
 	var products []*models.Product
 	for rows.Next() {
 		p := &models.Product{}
-		if err := rows.Scan(&p.ID, &p.Name, &p.Description, &p.Price, &p.Inventory, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		if err := rows.Scan(&p.ID, &p.SKU, &p.Name, &p.Description, &p.Price, &p.Inventory, &p.CreatedAt, &p.UpdatedAt, &p.Rating, &p.ReviewCount); err != nil {
 			return nil, err
 		}
 		products = append(products, p)
@@ -85,6 +322,339 @@ func (r *productRepository) GetByIDs(ctx context.Context, ids []int64) ([]*model
 	return products, nil
 }
 
+func (r *productRepository) Update(ctx context.Context, id int64, req *models.UpdateProductRequest) (*models.Product, error) {
+	query := `
+		UPDATE products
+		SET name = COALESCE($1, name),
+			description = COALESCE($2, description),
+			price = COALESCE($3, price),
+			inventory = COALESCE($4, inventory),
+			sku = COALESCE(NULLIF($6, ''), sku),
+			updated_at = NOW()
+		WHERE id = $5 AND deleted_at IS NULL
+		RETURNING id, COALESCE(sku, ''), name, description, price, inventory, created_at, updated_at, ` + productRatingColumns + `, attributes`
+
+	p := &models.Product{}
+	err := r.pool.QueryRow(ctx, query, req.Name, req.Description, req.Price, req.Inventory, id, req.SKU).
+		Scan(&p.ID, &p.SKU, &p.Name, &p.Description, &p.Price, &p.Inventory, &p.CreatedAt, &p.UpdatedAt, &p.Rating, &p.ReviewCount, &p.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Delete только помечает товар удалённым: строка остаётся, чтобы старые заказы
+// продолжали ссылаться на неё через order_items.
+func (r *productRepository) Delete(ctx context.Context, id int64) error {
+	query := `
+		UPDATE products
+		SET deleted_at = NOW(),
+			awaiting_offer = FALSE,
+			updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL`
+
+	result, err := r.pool.Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// Search ищет по search_vector с русской морфологией. Каждое слово запроса
+// ищется как префикс, чтобы поиск работал по мере ввода.
+func (r *productRepository) Search(ctx context.Context, query string, limit int) ([]*models.ProductSearchResult, error) {
+	tsQuery := buildPrefixTSQuery(query)
+	if tsQuery == "" {
+		return []*models.ProductSearchResult{}, nil
+	}
+
+	if limit <= 0 {
+		limit = defaultSearchResultSize
+	}
+
+	sql := `
+		SELECT
+			p.id, COALESCE(p.sku, ''), p.name, p.description, p.price, p.inventory, p.created_at, p.updated_at, ` + productRatingColumns + `,
+			ts_rank(p.search_vector, q) AS rank,
+			ts_headline('russian', p.name, q, 'StartSel=<b>, StopSel=</b>, HighlightAll=true'),
+			ts_headline('russian', coalesce(p.description, ''), q, 'StartSel=<b>, StopSel=</b>, MaxWords=35, MinWords=15')
+		FROM products p, to_tsquery('russian', $1) q
+		WHERE p.search_vector @@ q AND p.deleted_at IS NULL
+		ORDER BY rank DESC, p.id
+		LIMIT $2`
+
+	rows, err := r.pool.Query(ctx, sql, tsQuery, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]*models.ProductSearchResult, 0)
+	for rows.Next() {
+		res := &models.ProductSearchResult{}
+		p := &res.Product
+		if err := rows.Scan(
+			&p.ID, &p.SKU, &p.Name, &p.Description, &p.Price, &p.Inventory, &p.CreatedAt, &p.UpdatedAt, &p.Rating, &p.ReviewCount,
+			&res.Rank, &res.NameHighlight, &res.Snippet,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// UpsertBySKU отправляет все строки одним батчем и для каждой возвращает
+// ImportCreated, ImportUpdated, ImportUnchanged или ImportDeleted.
+func (r *productRepository) UpsertBySKU(ctx context.Context, tx pgx.Tx, reqs []*models.ImportProduct) ([]string, error) {
+	// Описание и остаток, которых нет в файле ($3 и $5 — NULL), у существующего
+	// товара остаются прежними, а новому товару задаются пустыми. Удалённый
+	// администратором товар не трогается; скрытый в ожидании цены из 1С
+	// с ценой из файла появляется в каталоге.
+	query := `
+		WITH existing AS (
+			SELECT deleted_at IS NOT NULL AND NOT awaiting_offer AS deleted
+			FROM products
+			WHERE sku = $1
+		), upserted AS (
+			INSERT INTO products (sku, name, description, price, inventory)
+			VALUES ($1, $2, COALESCE($3, ''), $4, COALESCE($5::int, 0))
+			ON CONFLICT (sku) DO UPDATE
+			SET name = EXCLUDED.name,
+				description = COALESCE($3, products.description),
+				price = EXCLUDED.price,
+				inventory = COALESCE($5::int, products.inventory),
+				deleted_at = NULL,
+				awaiting_offer = FALSE,
+				updated_at = NOW()
+			WHERE (products.deleted_at IS NULL OR products.awaiting_offer)
+				AND (products.name, products.description, products.price, products.inventory, products.deleted_at IS NULL)
+				IS DISTINCT FROM (EXCLUDED.name, COALESCE($3, products.description), EXCLUDED.price, COALESCE($5::int, products.inventory), true)
+			RETURNING (xmax = 0) AS inserted
+		)
+		SELECT (SELECT inserted FROM upserted), COALESCE((SELECT deleted FROM existing), false)`
+
+	batch := &pgx.Batch{}
+	for _, req := range reqs {
+		batch.Queue(query, req.SKU, req.Name, req.Description, req.Price, req.Inventory)
+	}
+
+	results := tx.SendBatch(ctx, batch)
+	defer results.Close()
+
+	statuses := make([]string, len(reqs))
+	for i := range reqs {
+		var inserted *bool
+		var deleted bool
+		err := results.QueryRow().Scan(&inserted, &deleted)
+		switch {
+		case err != nil:
+			return nil, fmt.Errorf("sku %s: %w", reqs[i].SKU, err)
+		case inserted != nil && *inserted:
+			statuses[i] = models.ImportCreated
+		case inserted != nil:
+			statuses[i] = models.ImportUpdated
+		case deleted:
+			statuses[i] = models.ImportDeleted
+		default:
+			statuses[i] = models.ImportUnchanged
+		}
+	}
+
+	return statuses, results.Close()
+}
+
+// ForEach проходит по всему каталогу, не загружая его в память целиком.
+func (r *productRepository) ForEach(ctx context.Context, fn func(p *models.Product) error) error {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, COALESCE(sku, ''), name, COALESCE(description, ''), price, inventory, created_at, updated_at,
+			ARRAY(SELECT category_id FROM product_categories WHERE product_id = p.id ORDER BY category_id)
+		FROM products p
+		WHERE deleted_at IS NULL
+		ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p := &models.Product{}
+		if err := rows.Scan(&p.ID, &p.SKU, &p.Name, &p.Description, &p.Price, &p.Inventory, &p.CreatedAt, &p.UpdatedAt, &p.CategoryIDs); err != nil {
+			return err
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// CatalogVersion меняется при любом изменении товаров, категорий и их связей:
+// счётчик увеличивают триггеры при коммите, поэтому версия растёт в порядке
+// коммитов и не пропускает поздно закоммиченные транзакции.
+func (r *productRepository) CatalogVersion(ctx context.Context) (string, error) {
+	var version int64
+	err := r.pool.QueryRow(ctx, `SELECT version FROM catalog_version`).Scan(&version)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(version, 10), nil
+}
+
+// UpsertExternal создаёт или обновляет товары по Ид из 1С. Товар, заведённый
+// вручную с тем же артикулом, привязывается к 1С вместо создания дубля.
+// Новые товары создаются с нулевой ценой: цены приходят отдельно в offers.xml.
+func (r *productRepository) UpsertExternal(ctx context.Context, tx pgx.Tx, products []models.ExternalProduct) error {
+	linkQuery := `
+		UPDATE products
+		SET external_id = $1
+		WHERE sku = $2 AND external_id IS NULL`
+
+	// Новый товар скрыт, пока offers.xml не задаст ему цену. Удаление
+	// администратором синхронизация не отменяет.
+	upsertQuery := `
+		INSERT INTO products (external_id, sku, name, description, price, inventory, deleted_at, awaiting_offer)
+		VALUES ($1, NULLIF($2, ''), $3, $4, 0, 0, NOW(), TRUE)
+		ON CONFLICT (external_id) DO UPDATE
+		SET sku = COALESCE(EXCLUDED.sku, products.sku),
+			name = EXCLUDED.name,
+			description = EXCLUDED.description,
+			updated_at = NOW()`
+
+	batch := &pgx.Batch{}
+	for _, p := range products {
+		if p.SKU != "" {
+			batch.Queue(linkQuery, p.ExternalID, p.SKU)
+		}
+		batch.Queue(upsertQuery, p.ExternalID, p.SKU, p.Name, p.Description)
+	}
+
+	return tx.SendBatch(ctx, batch).Close()
+}
+
+// UpdateExternalOffers обновляет цены и остатки и возвращает число товаров,
+// найденных по Ид. Товар, ждавший цены, с первой ценой появляется в каталоге.
+func (r *productRepository) UpdateExternalOffers(ctx context.Context, tx pgx.Tx, offers []models.ExternalOffer) (int, error) {
+	query := `
+		UPDATE products
+		SET price = COALESCE($2, price),
+			inventory = COALESCE($3, inventory),
+			deleted_at = CASE WHEN awaiting_offer AND $2 > 0 THEN NULL ELSE deleted_at END,
+			awaiting_offer = awaiting_offer AND NOT COALESCE($2 > 0, FALSE),
+			updated_at = NOW()
+		WHERE external_id = $1`
+
+	batch := &pgx.Batch{}
+	for _, o := range offers {
+		batch.Queue(query, o.ExternalID, o.Price, o.Quantity)
+	}
+
+	results := tx.SendBatch(ctx, batch)
+	defer results.Close()
+
+	updated := 0
+	for range offers {
+		tag, err := results.Exec()
+		if err != nil {
+			return 0, err
+		}
+		updated += int(tag.RowsAffected())
+	}
+
+	return updated, results.Close()
+}
+
+func (r *productRepository) SetAttributes(ctx context.Context, id int64, attributes map[string]any) error {
+	result, err := r.pool.Exec(ctx, `
+		UPDATE products
+		SET attributes = $2,
+			updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL`, id, attributes)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// FacetCounts считает, сколько товаров под фильтрами params имеют каждое
+// значение атрибутов codes.
+func (r *productRepository) FacetCounts(ctx context.Context, params *models.ProductListParams, codes []string, skipAttr string) (map[string][]models.FacetValue, error) {
+	facets := make(map[string][]models.FacetValue)
+	if len(codes) == 0 {
+		return facets, nil
+	}
+
+	args := make([]any, 0)
+	addArg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conditions := productFilterConditions(params, skipAttr, addArg)
+	conditions = append(conditions, "kv.key = ANY("+addArg(codes)+")")
+
+	query := fmt.Sprintf(`
+		SELECT kv.key, kv.value, COUNT(*)
+		FROM products, jsonb_each_text(attributes) kv
+		WHERE %s AND kv.value IS NOT NULL
+		GROUP BY kv.key, kv.value
+		ORDER BY kv.key, COUNT(*) DESC, kv.value`, strings.Join(conditions, " AND "))
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var code string
+		var fv models.FacetValue
+		if err := rows.Scan(&code, &fv.Value, &fv.Count); err != nil {
+			return nil, err
+		}
+		facets[code] = append(facets[code], fv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return facets, nil
+}
+
+// InvalidateCache — кэша у базового репозитория нет, см. NewCachedProductRepository.
+func (r *productRepository) InvalidateCache(ctx context.Context, ids ...int64) {}
+
+// buildPrefixTSQuery превращает пользовательский ввод в запрос вида
+// "слово1:* & слово2:*", отбрасывая всё, кроме букв и цифр, чтобы спецсимволы
+// tsquery не ломали разбор.
+func buildPrefixTSQuery(input string) string {
+	words := strings.FieldsFunc(input, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, w := range words {
+		terms = append(terms, strings.ToLower(w)+":*")
+	}
+
+	return strings.Join(terms, " & ")
+}
+
 func NewProductRepository(pool *pgxpool.Pool) ProductRepository {
 	return &productRepository{pool: pool}
 }
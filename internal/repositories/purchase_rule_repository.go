@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"context"
+	"ecommerce-api/internal/models"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PurchaseRuleRepository interface {
+	// Get возвращает nil, если у товара нет правил.
+	Get(ctx context.Context, productID int64) (*models.PurchaseRules, error)
+	GetByProductIDs(ctx context.Context, productIDs []int64) (map[int64]*models.PurchaseRules, error)
+	// Set и Delete обновляют updated_at товара, чтобы его карточка не отдавалась из кэша клиента.
+	Set(ctx context.Context, productID int64, rules *models.PurchaseRules) error
+	Delete(ctx context.Context, productID int64) error
+	// PurchasedQuantity считает, сколько штук товара пользователь заказал
+	// начиная с since; отменённые и возвращённые заказы не учитываются.
+	PurchasedQuantity(ctx context.Context, userID int64, productID int64, since time.Time) (int, error)
+}
+
+type purchaseRuleRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPurchaseRuleRepository(pool *pgxpool.Pool) PurchaseRuleRepository {
+	return &purchaseRuleRepository{pool: pool}
+}
+
+const purchaseRuleColumns = "product_id, min_quantity, max_quantity, quantity_step, customer_limit, customer_limit_days"
+
+func scanPurchaseRules(row pgx.Row) (int64, *models.PurchaseRules, error) {
+	var productID int64
+	var rules models.PurchaseRules
+	err := row.Scan(&productID, &rules.MinQuantity, &rules.MaxQuantity, &rules.QuantityStep, &rules.CustomerLimit, &rules.CustomerLimitDays)
+	if err != nil {
+		return 0, nil, err
+	}
+	return productID, &rules, nil
+}
+
+func (r *purchaseRuleRepository) Get(ctx context.Context, productID int64) (*models.PurchaseRules, error) {
+	_, rules, err := scanPurchaseRules(r.pool.QueryRow(ctx, `
+		SELECT `+purchaseRuleColumns+`
+		FROM product_purchase_rules
+		WHERE product_id = $1`, productID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return rules, err
+}
+
+func (r *purchaseRuleRepository) GetByProductIDs(ctx context.Context, productIDs []int64) (map[int64]*models.PurchaseRules, error) {
+	result := make(map[int64]*models.PurchaseRules)
+	if len(productIDs) == 0 {
+		return result, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+purchaseRuleColumns+`
+		FROM product_purchase_rules
+		WHERE product_id = ANY($1)`, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		productID, rules, err := scanPurchaseRules(rows)
+		if err != nil {
+			return nil, err
+		}
+		result[productID] = rules
+	}
+	return result, rows.Err()
+}
+
+func (r *purchaseRuleRepository) Set(ctx context.Context, productID int64, rules *models.PurchaseRules) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE products
+		SET updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL`, productID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO product_purchase_rules (`+purchaseRuleColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (product_id) DO UPDATE
+		SET min_quantity = EXCLUDED.min_quantity,
+			max_quantity = EXCLUDED.max_quantity,
+			quantity_step = EXCLUDED.quantity_step,
+			customer_limit = EXCLUDED.customer_limit,
+			customer_limit_days = EXCLUDED.customer_limit_days,
+			updated_at = NOW()`,
+		productID, rules.MinQuantity, rules.MaxQuantity, rules.QuantityStep, rules.CustomerLimit, rules.CustomerLimitDays)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *purchaseRuleRepository) Delete(ctx context.Context, productID int64) error {
+	_, err := r.pool.Exec(ctx, `
+		WITH deleted AS (
+			DELETE FROM product_purchase_rules
+			WHERE product_id = $1
+			RETURNING product_id
+		)
+		UPDATE products
+		SET updated_at = NOW()
+		WHERE id IN (SELECT product_id FROM deleted)`, productID)
+	return err
+}
+
+func (r *purchaseRuleRepository) PurchasedQuantity(ctx context.Context, userID int64, productID int64, since time.Time) (int, error) {
+	var quantity int
+	err := r.pool.QueryRow(ctx, `
+		SELECT COALESCE(SUM(oi.quantity), 0)
+		FROM orders o
+		JOIN order_items oi ON oi.order_id = o.id
+		WHERE o.user_id = $1 AND oi.product_id = $2 AND o.created_at >= $3 AND o.status NOT IN ('canceled', 'refunded')`,
+		userID, productID, since).Scan(&quantity)
+	return quantity, err
+}
@@ -0,0 +1,144 @@
+package repositories
+
+import (
+	"context"
+	"ecommerce-api/internal/models"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrRelatedProductNotFound = errors.New("related product not found")
+
+type RelatedRepository interface {
+	SetLinks(ctx context.Context, productID int64, relatedIDs []int64) error
+	ListLinks(ctx context.Context, productIDs []int64) ([]models.ProductLink, error)
+	ListCopurchases(ctx context.Context, productIDs []int64) ([]models.ProductLink, error)
+	RefreshCopurchases(ctx context.Context, minOrders int, perProduct int) (int64, error)
+}
+
+type relatedRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewRelatedRepository(pool *pgxpool.Pool) RelatedRepository {
+	return &relatedRepository{pool: pool}
+}
+
+// SetLinks заменяет ручные связи товара; порядок relatedIDs задаёт позиции.
+func (r *relatedRepository) SetLinks(ctx context.Context, productID int64, relatedIDs []int64) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM product_links WHERE product_id = $1`, productID); err != nil {
+		return err
+	}
+
+	if len(relatedIDs) > 0 {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO product_links (product_id, related_id, position)
+			SELECT $1, related_id, position - 1
+			FROM unnest($2::bigint[]) WITH ORDINALITY AS t(related_id, position)`, productID, relatedIDs)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+				return ErrRelatedProductNotFound
+			}
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *relatedRepository) ListLinks(ctx context.Context, productIDs []int64) ([]models.ProductLink, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT product_id, related_id, position
+		FROM product_links
+		WHERE product_id = ANY($1)
+		ORDER BY position, related_id`, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	return scanProductLinks(rows)
+}
+
+func (r *relatedRepository) ListCopurchases(ctx context.Context, productIDs []int64) ([]models.ProductLink, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT product_id, related_id, orders_count
+		FROM product_copurchases
+		WHERE product_id = ANY($1)
+		ORDER BY orders_count DESC, related_id`, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	return scanProductLinks(rows)
+}
+
+// RefreshCopurchases пересчитывает пары товаров из оплаченных заказов.
+// Для каждого товара хранятся только perProduct самых частых пар.
+// Пересчёт идёт в транзакции, так что читатели до коммита видят старые данные.
+func (r *relatedRepository) RefreshCopurchases(ctx context.Context, minOrders int, perProduct int) (int64, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM product_copurchases`); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(ctx, `
+		INSERT INTO product_copurchases (product_id, related_id, orders_count)
+		SELECT product_id, related_id, orders_count
+		FROM (
+			SELECT
+				a.product_id,
+				b.product_id AS related_id,
+				COUNT(DISTINCT a.order_id) AS orders_count,
+				ROW_NUMBER() OVER (
+					PARTITION BY a.product_id
+					ORDER BY COUNT(DISTINCT a.order_id) DESC, b.product_id
+				) AS rank
+			FROM order_items a
+			JOIN order_items b ON b.order_id = a.order_id AND b.product_id <> a.product_id
+			JOIN orders o ON o.id = a.order_id
+			WHERE o.status IN ('paid', 'processing', 'shipped', 'delivered')
+			GROUP BY a.product_id, b.product_id
+			HAVING COUNT(DISTINCT a.order_id) >= $1
+		) ranked
+		WHERE rank <= $2`, minOrders, perProduct)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
+
+func scanProductLinks(rows pgx.Rows) ([]models.ProductLink, error) {
+	defer rows.Close()
+
+	links := make([]models.ProductLink, 0)
+	for rows.Next() {
+		var l models.ProductLink
+		if err := rows.Scan(&l.ProductID, &l.RelatedID, &l.Score); err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
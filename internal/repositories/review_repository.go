@@ -0,0 +1,161 @@
+package repositories
+
+import (
+	"context"
+	"ecommerce-api/internal/models"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrReviewExists = errors.New("review for this product already exists")
+
+type ReviewRepository interface {
+	Create(ctx context.Context, review *models.Review) error
+	HasPurchased(ctx context.Context, userID int64, productID int64) (bool, error)
+	ListApproved(ctx context.Context, productID int64, after int64, limit int) ([]*models.Review, error)
+	ListByStatus(ctx context.Context, status string, after int64, limit int) ([]*models.Review, error)
+	SetStatus(ctx context.Context, id int64, status string) (*models.Review, error)
+}
+
+type reviewRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewReviewRepository(pool *pgxpool.Pool) ReviewRepository {
+	return &reviewRepository{pool: pool}
+}
+
+func (r *reviewRepository) Create(ctx context.Context, review *models.Review) error {
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO product_reviews (product_id, user_id, rating, body)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, status, created_at`,
+		review.ProductID, review.UserID, review.Rating, review.Body,
+	).Scan(&review.ID, &review.Status, &review.CreatedAt)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrReviewExists
+	}
+	return err
+}
+
+// HasPurchased проверяет, что у пользователя есть оплаченный заказ с этим товаром.
+func (r *reviewRepository) HasPurchased(ctx context.Context, userID int64, productID int64) (bool, error) {
+	var exists bool
+	err := r.pool.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM orders o
+			JOIN order_items oi ON oi.order_id = o.id
+			WHERE o.user_id = $1 AND oi.product_id = $2 AND o.status IN ('paid', 'processing', 'shipped', 'delivered')
+		)`, userID, productID).Scan(&exists)
+	return exists, err
+}
+
+func (r *reviewRepository) ListApproved(ctx context.Context, productID int64, after int64, limit int) ([]*models.Review, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, product_id, user_id, rating, body, status, moderated_at, created_at
+		FROM product_reviews
+		WHERE product_id = $1 AND status = 'approved' AND id > $2
+		ORDER BY id
+		LIMIT $3`, productID, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanReviews(rows)
+}
+
+func (r *reviewRepository) ListByStatus(ctx context.Context, status string, after int64, limit int) ([]*models.Review, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, product_id, user_id, rating, body, status, moderated_at, created_at
+		FROM product_reviews
+		WHERE status = $1 AND id > $2
+		ORDER BY id
+		LIMIT $3`, status, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanReviews(rows)
+}
+
+// SetStatus меняет статус отзыва и в той же транзакции поправляет счётчики
+// рейтинга товара: в них учитываются только одобренные отзывы.
+func (r *reviewRepository) SetStatus(ctx context.Context, id int64, status string) (*models.Review, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var oldStatus string
+	err = tx.QueryRow(ctx, `SELECT status FROM product_reviews WHERE id = $1 FOR UPDATE`, id).Scan(&oldStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `
+		UPDATE product_reviews
+		SET status = $2,
+			moderated_at = NOW()
+		WHERE id = $1
+		RETURNING id, product_id, user_id, rating, body, status, moderated_at, created_at`, id, status)
+	if err != nil {
+		return nil, err
+	}
+
+	reviews, err := scanReviews(rows)
+	if err != nil {
+		return nil, err
+	}
+	review := reviews[0]
+
+	delta := 0
+	switch {
+	case oldStatus != models.ReviewApproved && status == models.ReviewApproved:
+		delta = 1
+	case oldStatus == models.ReviewApproved && status != models.ReviewApproved:
+		delta = -1
+	}
+
+	if delta != 0 {
+		_, err = tx.Exec(ctx, `
+			UPDATE products
+			SET rating_sum = rating_sum + $2,
+				rating_count = rating_count + $3,
+				updated_at = NOW()
+			WHERE id = $1`, review.ProductID, delta*review.Rating, delta)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return review, nil
+}
+
+func scanReviews(rows pgx.Rows) ([]*models.Review, error) {
+	defer rows.Close()
+
+	reviews := make([]*models.Review, 0)
+	for rows.Next() {
+		rv := &models.Review{}
+		if err := rows.Scan(
+			&rv.ID, &rv.ProductID, &rv.UserID, &rv.Rating, &rv.Body, &rv.Status, &rv.ModeratedAt, &rv.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, rv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reviews, nil
+}
@@ -0,0 +1,214 @@
+package repositories
+
+import (
+	"context"
+	"ecommerce-api/internal/models"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrVariantSKUTaken = errors.New("variant sku already exists")
+
+type VariantRepository interface {
+	ListOptions(ctx context.Context, productID int64) ([]*models.ProductOption, error)
+	SetOptions(ctx context.Context, productID int64, options []models.ProductOptionInput) error
+	ListByProduct(ctx context.Context, productID int64) ([]*models.ProductVariant, error)
+	GetByIDs(ctx context.Context, ids []int64) ([]*models.ProductVariant, error)
+	// ListByProducts возвращает варианты нескольких товаров, по товарам и id.
+	ListByProducts(ctx context.Context, productIDs []int64) ([]*models.ProductVariant, error)
+	Create(ctx context.Context, productID int64, req *models.CreateVariantRequest) (int64, error)
+	Update(ctx context.Context, productID int64, variantID int64, req *models.UpdateVariantRequest) (*models.ProductVariant, error)
+	Delete(ctx context.Context, productID int64, variantID int64) error
+}
+
+type variantRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewVariantRepository(pool *pgxpool.Pool) VariantRepository {
+	return &variantRepository{pool: pool}
+}
+
+func (r *variantRepository) ListOptions(ctx context.Context, productID int64) ([]*models.ProductOption, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, product_id, name, values, position
+		FROM product_options
+		WHERE product_id = $1
+		ORDER BY position, id`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	options := make([]*models.ProductOption, 0)
+	for rows.Next() {
+		o := &models.ProductOption{}
+		if err := rows.Scan(&o.ID, &o.ProductID, &o.Name, &o.Values, &o.Position); err != nil {
+			return nil, err
+		}
+		options = append(options, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return options, nil
+}
+
+// SetOptions полностью заменяет набор опций товара.
+func (r *variantRepository) SetOptions(ctx context.Context, productID int64, options []models.ProductOptionInput) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM product_options WHERE product_id = $1`, productID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE products SET updated_at = NOW() WHERE id = $1`, productID); err != nil {
+		return err
+	}
+
+	for i, o := range options {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO product_options (product_id, name, values, position)
+			VALUES ($1, $2, $3, $4)`, productID, o.Name, o.Values, i)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *variantRepository) ListByProduct(ctx context.Context, productID int64) ([]*models.ProductVariant, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, product_id, sku, price, inventory, options, created_at, updated_at
+		FROM product_variants
+		WHERE product_id = $1 AND deleted_at IS NULL
+		ORDER BY id`, productID)
+	if err != nil {
+		return nil, err
+	}
+	return scanVariants(rows)
+}
+
+func (r *variantRepository) GetByIDs(ctx context.Context, ids []int64) ([]*models.ProductVariant, error) {
+	if len(ids) == 0 {
+		return []*models.ProductVariant{}, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, product_id, sku, price, inventory, options, created_at, updated_at
+		FROM product_variants
+		WHERE id = ANY($1) AND deleted_at IS NULL
+		ORDER BY id`, ids)
+	if err != nil {
+		return nil, err
+	}
+	return scanVariants(rows)
+}
+
+func (r *variantRepository) ListByProducts(ctx context.Context, productIDs []int64) ([]*models.ProductVariant, error) {
+	if len(productIDs) == 0 {
+		return []*models.ProductVariant{}, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, product_id, sku, price, inventory, options, created_at, updated_at
+		FROM product_variants
+		WHERE product_id = ANY($1) AND deleted_at IS NULL
+		ORDER BY product_id, id`, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	return scanVariants(rows)
+}
+
+func (r *variantRepository) Create(ctx context.Context, productID int64, req *models.CreateVariantRequest) (int64, error) {
+	var id int64
+	err := r.pool.QueryRow(ctx, `
+		WITH touched AS (
+			UPDATE products SET updated_at = NOW() WHERE id = $1
+		)
+		INSERT INTO product_variants (product_id, sku, price, inventory, options)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`,
+		productID, req.SKU, req.Price, req.Inventory, req.Options).Scan(&id)
+	if err != nil {
+		return 0, mapVariantError(err)
+	}
+	return id, nil
+}
+
+func (r *variantRepository) Update(ctx context.Context, productID int64, variantID int64, req *models.UpdateVariantRequest) (*models.ProductVariant, error) {
+	query := `
+		WITH touched AS (
+			UPDATE products SET updated_at = NOW() WHERE id = $5
+		)
+		UPDATE product_variants
+		SET sku = COALESCE($1, sku),
+			price = COALESCE($2, price),
+			inventory = COALESCE($3, inventory),
+			updated_at = NOW()
+		WHERE id = $4 AND product_id = $5 AND deleted_at IS NULL
+		RETURNING id, product_id, sku, price, inventory, options, created_at, updated_at`
+
+	v := &models.ProductVariant{}
+	err := r.pool.QueryRow(ctx, query, req.SKU, req.Price, req.Inventory, variantID, productID).
+		Scan(&v.ID, &v.ProductID, &v.SKU, &v.Price, &v.Inventory, &v.Options, &v.CreatedAt, &v.UpdatedAt)
+	if err != nil {
+		return nil, mapVariantError(err)
+	}
+	return v, nil
+}
+
+// Delete помечает вариант удалённым, чтобы order_items продолжали на него ссылаться.
+func (r *variantRepository) Delete(ctx context.Context, productID int64, variantID int64) error {
+	result, err := r.pool.Exec(ctx, `
+		WITH touched AS (
+			UPDATE products SET updated_at = NOW() WHERE id = $2
+		)
+		UPDATE product_variants
+		SET deleted_at = NOW(),
+			updated_at = NOW()
+		WHERE id = $1 AND product_id = $2 AND deleted_at IS NULL`, variantID, productID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func scanVariants(rows pgx.Rows) ([]*models.ProductVariant, error) {
+	defer rows.Close()
+
+	variants := make([]*models.ProductVariant, 0)
+	for rows.Next() {
+		v := &models.ProductVariant{}
+		if err := rows.Scan(&v.ID, &v.ProductID, &v.SKU, &v.Price, &v.Inventory, &v.Options, &v.CreatedAt, &v.UpdatedAt); err != nil {
+			return nil, err
+		}
+		variants = append(variants, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return variants, nil
+}
+
+func mapVariantError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrVariantSKUTaken
+	}
+	return err
+}
@@ -0,0 +1,248 @@
+package repositories
+
+import (
+	"context"
+	"ecommerce-api/internal/models"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WishlistRepository interface {
+	Create(ctx context.Context, wishlist *models.Wishlist) error
+	// GetDefault возвращает список «Отложено» пользователя, создавая его при первом обращении.
+	GetDefault(ctx context.Context, userID int64) (*models.Wishlist, error)
+	ListByUser(ctx context.Context, userID int64) ([]*models.Wishlist, error)
+	// GetByID находит список только среди списков userID, чужой даёт pgx.ErrNoRows.
+	GetByID(ctx context.Context, id int64, userID int64) (*models.Wishlist, error)
+	GetByShareToken(ctx context.Context, token string) (*models.Wishlist, error)
+	// Update сохраняет имя и ссылку для публикации.
+	Update(ctx context.Context, wishlist *models.Wishlist) error
+	Delete(ctx context.Context, id int64, userID int64) error
+	ListItems(ctx context.Context, wishlistID int64) ([]models.WishlistItem, error)
+	// GetItemQuantity возвращает количество товара в списке, 0 — если его там нет.
+	GetItemQuantity(ctx context.Context, wishlistID int64, item models.CartItemKey) (int, error)
+	// AddItem добавляет товар; количество уже отложенного товара увеличивается.
+	AddItem(ctx context.Context, wishlistID int64, item models.CartItemKey, quantity int) error
+	RemoveItem(ctx context.Context, wishlistID int64, item models.CartItemKey) (bool, error)
+	// TakeItem уменьшает количество отложенного товара на quantity;
+	// позиция удаляется, когда количество кончается.
+	TakeItem(ctx context.Context, wishlistID int64, item models.CartItemKey, quantity int) error
+}
+
+type wishlistRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewWishlistRepository(pool *pgxpool.Pool) WishlistRepository {
+	return &wishlistRepository{pool: pool}
+}
+
+const wishlistColumns = `
+	w.id, w.user_id, w.name, w.is_default, w.share_token, w.created_at, w.updated_at,
+	(SELECT COUNT(*) FROM wishlist_items wi WHERE wi.wishlist_id = w.id)`
+
+func scanWishlist(row pgx.Row) (*models.Wishlist, error) {
+	var w models.Wishlist
+	err := row.Scan(&w.ID, &w.UserID, &w.Name, &w.IsDefault, &w.ShareToken, &w.CreatedAt, &w.UpdatedAt, &w.ItemCount)
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (r *wishlistRepository) Create(ctx context.Context, wishlist *models.Wishlist) error {
+	return r.pool.QueryRow(ctx, `
+		INSERT INTO wishlists (user_id, name)
+		VALUES ($1, $2)
+		RETURNING id, created_at, updated_at`,
+		wishlist.UserID, wishlist.Name,
+	).Scan(&wishlist.ID, &wishlist.CreatedAt, &wishlist.UpdatedAt)
+}
+
+func (r *wishlistRepository) GetDefault(ctx context.Context, userID int64) (*models.Wishlist, error) {
+	// DO UPDATE вместо DO NOTHING, чтобы RETURNING вернул и уже существующий список.
+	var id int64
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO wishlists (user_id, name, is_default)
+		VALUES ($1, $2, TRUE)
+		ON CONFLICT (user_id) WHERE is_default DO UPDATE
+		SET user_id = EXCLUDED.user_id
+		RETURNING id`, userID, models.DefaultWishlistName).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(ctx, id, userID)
+}
+
+func (r *wishlistRepository) ListByUser(ctx context.Context, userID int64) ([]*models.Wishlist, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+wishlistColumns+`
+		FROM wishlists w
+		WHERE w.user_id = $1
+		ORDER BY w.is_default DESC, w.id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	wishlists := make([]*models.Wishlist, 0)
+	for rows.Next() {
+		w, err := scanWishlist(rows)
+		if err != nil {
+			return nil, err
+		}
+		wishlists = append(wishlists, w)
+	}
+	return wishlists, rows.Err()
+}
+
+func (r *wishlistRepository) GetByID(ctx context.Context, id int64, userID int64) (*models.Wishlist, error) {
+	return scanWishlist(r.pool.QueryRow(ctx, `
+		SELECT `+wishlistColumns+`
+		FROM wishlists w
+		WHERE w.id = $1 AND w.user_id = $2`, id, userID))
+}
+
+func (r *wishlistRepository) GetByShareToken(ctx context.Context, token string) (*models.Wishlist, error) {
+	return scanWishlist(r.pool.QueryRow(ctx, `
+		SELECT `+wishlistColumns+`
+		FROM wishlists w
+		WHERE w.share_token = $1`, token))
+}
+
+func (r *wishlistRepository) Update(ctx context.Context, wishlist *models.Wishlist) error {
+	return r.pool.QueryRow(ctx, `
+		UPDATE wishlists
+		SET name = $3,
+			share_token = $4,
+			updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING updated_at`,
+		wishlist.ID, wishlist.UserID, wishlist.Name, wishlist.ShareToken,
+	).Scan(&wishlist.UpdatedAt)
+}
+
+func (r *wishlistRepository) Delete(ctx context.Context, id int64, userID int64) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM wishlists WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *wishlistRepository) ListItems(ctx context.Context, wishlistID int64) ([]models.WishlistItem, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT product_id, variant_id, quantity, added_at
+		FROM wishlist_items
+		WHERE wishlist_id = $1
+		ORDER BY added_at DESC, product_id, variant_id`, wishlistID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]models.WishlistItem, 0)
+	for rows.Next() {
+		var item models.WishlistItem
+		var variantID int64
+		if err := rows.Scan(&item.ProductID, &variantID, &item.Quantity, &item.AddedAt); err != nil {
+			return nil, err
+		}
+		if variantID != 0 {
+			item.VariantID = &variantID
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (r *wishlistRepository) GetItemQuantity(ctx context.Context, wishlistID int64, item models.CartItemKey) (int, error) {
+	var quantity int
+	err := r.pool.QueryRow(ctx, `
+		SELECT quantity
+		FROM wishlist_items
+		WHERE wishlist_id = $1 AND product_id = $2 AND variant_id = $3`,
+		wishlistID, item.ProductID, item.VariantID).Scan(&quantity)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	return quantity, err
+}
+
+func (r *wishlistRepository) AddItem(ctx context.Context, wishlistID int64, item models.CartItemKey, quantity int) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO wishlist_items (wishlist_id, product_id, variant_id, quantity)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (wishlist_id, product_id, variant_id) DO UPDATE
+		SET quantity = wishlist_items.quantity + EXCLUDED.quantity`,
+		wishlistID, item.ProductID, item.VariantID, quantity)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE wishlists SET updated_at = NOW() WHERE id = $1`, wishlistID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *wishlistRepository) TakeItem(ctx context.Context, wishlistID int64, item models.CartItemKey, quantity int) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		DELETE FROM wishlist_items
+		WHERE wishlist_id = $1 AND product_id = $2 AND variant_id = $3 AND quantity <= $4`,
+		wishlistID, item.ProductID, item.VariantID, quantity)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		_, err = tx.Exec(ctx, `
+			UPDATE wishlist_items
+			SET quantity = quantity - $4
+			WHERE wishlist_id = $1 AND product_id = $2 AND variant_id = $3`,
+			wishlistID, item.ProductID, item.VariantID, quantity)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE wishlists SET updated_at = NOW() WHERE id = $1`, wishlistID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *wishlistRepository) RemoveItem(ctx context.Context, wishlistID int64, item models.CartItemKey) (bool, error) {
+	result, err := r.pool.Exec(ctx, `
+		WITH removed AS (
+			DELETE FROM wishlist_items
+			WHERE wishlist_id = $1 AND product_id = $2 AND variant_id = $3
+			RETURNING wishlist_id
+		)
+		UPDATE wishlists
+		SET updated_at = NOW()
+		WHERE id IN (SELECT wishlist_id FROM removed)`,
+		wishlistID, item.ProductID, item.VariantID)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
@@ -1,6 +1,8 @@
 package server
 
 import (
+	"strings"
+
 	"ecommerce-api/internal/config"
 	"ecommerce-api/internal/handlers"
 	"ecommerce-api/internal/middlewares"
@@ -10,42 +12,146 @@ import (
 
 func NewRouter(
 	apiKeyConfig config.ApiKeyConfig,
+	storageConfig config.StorageConfig,
 	productHandler *handlers.ProductHandler,
 	cartHandler *handlers.CartHandler,
 	authHandler *handlers.AuthHandler,
 	authMiddleware gin.HandlerFunc,
+	optionalAuthMiddleware gin.HandlerFunc,
 	orderHandler *handlers.OrderHandler,
 	paymentHandler *handlers.PaymentHandler,
+	categoryHandler *handlers.CategoryHandler,
+	variantHandler *handlers.VariantHandler,
+	imageHandler *handlers.ImageHandler,
+	productCSVHandler *handlers.ProductCSVHandler,
+	feedHandler *handlers.FeedHandler,
+	exchangeHandler *handlers.ExchangeHandler,
+	reviewHandler *handlers.ReviewHandler,
+	attributeHandler *handlers.AttributeHandler,
+	priceHandler *handlers.PriceHandler,
+	recommendationHandler *handlers.RecommendationHandler,
+	wishlistHandler *handlers.WishlistHandler,
+	cartReminderHandler *handlers.CartReminderHandler,
 ) *gin.Engine {
 	r := gin.Default()
 
+	// В продакшене файлы отдаёт nginx или CDN по STORAGE_BASE_URL.
+	if storageConfig.ServeStatic && strings.HasPrefix(storageConfig.BaseURL, "/") {
+		r.Static(storageConfig.BaseURL, storageConfig.Dir)
+	}
+
 	r.POST("/register", authHandler.Register)
 	r.POST("/login", authHandler.Login)
 
 	r.POST("/products", middlewares.ApiKeyMiddleware(apiKeyConfig.Admin), productHandler.Create)
-	r.GET("/products", productHandler.List)
+	r.GET("/products", middlewares.ConditionalGET(), productHandler.List)
+	r.GET("/products/search", productHandler.Search)
+	r.GET("/products/facets", attributeHandler.Facets)
+	r.GET("/products/:id", middlewares.ConditionalGET(), productHandler.Get)
+	r.PATCH("/products/:id", middlewares.ApiKeyMiddleware(apiKeyConfig.Admin), productHandler.Update)
+	r.DELETE("/products/:id", middlewares.ApiKeyMiddleware(apiKeyConfig.Admin), productHandler.Delete)
+	r.GET("/products/:id/reviews", reviewHandler.List)
+	r.GET("/products/:id/price-history", priceHandler.History)
+	r.GET("/products/:id/related", recommendationHandler.Related)
+	r.POST("/products/:id/reviews", authMiddleware, reviewHandler.Create)
+
+	adminProduct := r.Group("/products/:id")
+	adminProduct.Use(middlewares.ApiKeyMiddleware(apiKeyConfig.Admin))
+	{
+		adminProduct.PUT("/options", variantHandler.SetOptions)
+		adminProduct.PUT("/attributes", attributeHandler.SetProductAttributes)
+		adminProduct.PUT("/related", recommendationHandler.SetRelated)
+		adminProduct.PUT("/purchase-rules", productHandler.SetPurchaseRules)
+		adminProduct.DELETE("/purchase-rules", productHandler.DeletePurchaseRules)
+		adminProduct.GET("/scheduled-prices", priceHandler.ListScheduled)
+		adminProduct.POST("/scheduled-prices", priceHandler.Schedule)
+		adminProduct.DELETE("/scheduled-prices/:schedule_id", priceHandler.CancelScheduled)
+		adminProduct.POST("/variants", variantHandler.Create)
+		adminProduct.PATCH("/variants/:variant_id", variantHandler.Update)
+		adminProduct.DELETE("/variants/:variant_id", variantHandler.Delete)
+		adminProduct.POST("/images", imageHandler.Upload)
+		adminProduct.PATCH("/images/:image_id", imageHandler.Update)
+		adminProduct.DELETE("/images/:image_id", imageHandler.Delete)
+	}
+
+	r.GET("/attributes", attributeHandler.List)
+	r.POST("/attributes", middlewares.ApiKeyMiddleware(apiKeyConfig.Admin), attributeHandler.Create)
+	r.DELETE("/attributes/:id", middlewares.ApiKeyMiddleware(apiKeyConfig.Admin), attributeHandler.Delete)
+
+	r.GET("/categories", categoryHandler.Tree)
+	r.GET("/categories/:id/products", categoryHandler.Products)
+
+	adminCategories := r.Group("/categories")
+	adminCategories.Use(middlewares.ApiKeyMiddleware(apiKeyConfig.Admin))
+	{
+		adminCategories.POST("", categoryHandler.Create)
+		adminCategories.PATCH("/:id", categoryHandler.Update)
+		adminCategories.DELETE("/:id", categoryHandler.Delete)
+		adminCategories.POST("/:id/products", categoryHandler.AddProducts)
+		adminCategories.DELETE("/:id/products/:product_id", categoryHandler.RemoveProduct)
+	}
+
+	r.GET("/feeds/yandex.yml", feedHandler.YandexMarket)
+
+	// 1С авторизуется через Basic Auth в режиме checkauth, дальше — по cookie сессии.
+	r.GET("/1c_exchange", exchangeHandler.Handle)
+	r.POST("/1c_exchange", exchangeHandler.Handle)
+
+	admin := r.Group("/admin")
+	admin.Use(middlewares.ApiKeyMiddleware(apiKeyConfig.Admin))
+	{
+		admin.POST("/products/import", productCSVHandler.Import)
+		admin.GET("/products/export", productCSVHandler.Export)
+		admin.GET("/reviews", reviewHandler.ListForModeration)
+		admin.POST("/reviews/:id/approve", reviewHandler.Approve)
+		admin.POST("/reviews/:id/reject", reviewHandler.Reject)
+		admin.POST("/orders/:id/status", orderHandler.UpdateStatus)
+	}
 
 	r.GET("/success", orderHandler.PaymentSuccess)
 	r.GET("/fail", orderHandler.PaymentFail)
 
 	r.POST("/webhook/yookassa", paymentHandler.HandleWebhook)
 
+	// Корзина доступна и гостям: их корзина определяется по токену корзины.
 	cart := r.Group("/cart")
-	cart.Use(authMiddleware)
+	cart.Use(optionalAuthMiddleware)
 	{
 		cart.POST("/items", cartHandler.AddToCart)
 		cart.PUT("/items/:product_id", cartHandler.UpdateCartItem)
 		cart.DELETE("/items/:product_id", cartHandler.RemoveFromCart)
+		cart.POST("/items/:product_id/save-for-later", wishlistHandler.SaveForLater)
+		cart.PUT("/reminders", cartReminderHandler.SetEnabled)
+		cart.GET("/reminders/unsubscribe", cartReminderHandler.Unsubscribe)
 		cart.GET("", cartHandler.GetCart)
 		cart.DELETE("", cartHandler.ClearCart)
+		cart.POST("/share", cartHandler.ShareCart)
+		cart.POST("/import/:token", cartHandler.ImportCart)
+	}
+
+	// Опубликованный список открывается по ссылке без входа.
+	r.GET("/wishlists/shared/:token", wishlistHandler.GetShared)
+
+	wishlists := r.Group("/wishlists")
+	wishlists.Use(authMiddleware)
+	{
+		wishlists.GET("", wishlistHandler.List)
+		wishlists.POST("", wishlistHandler.Create)
+		wishlists.GET("/:id", wishlistHandler.Get)
+		wishlists.PATCH("/:id", wishlistHandler.Update)
+		wishlists.DELETE("/:id", wishlistHandler.Delete)
+		wishlists.POST("/:id/items", wishlistHandler.AddItem)
+		wishlists.DELETE("/:id/items/:product_id", wishlistHandler.RemoveItem)
+		wishlists.POST("/:id/items/:product_id/move-to-cart", wishlistHandler.MoveToCart)
 	}
 
 	orders := r.Group("/orders")
 	orders.Use(authMiddleware)
 	{
 		orders.POST("", orderHandler.CreateOrder)
-		orders.GET("", orderHandler.ListOrders)
-		orders.GET("/:id", orderHandler.GetOrder)
+		orders.GET("", middlewares.ConditionalGET(), orderHandler.ListOrders)
+		orders.GET("/:id", middlewares.ConditionalGET(), orderHandler.GetOrder)
+		orders.GET("/:id/history", orderHandler.GetOrderHistory)
 	}
 
 	return r
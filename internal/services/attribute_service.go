@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"ecommerce-api/internal/models"
+	"ecommerce-api/internal/repositories"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+)
+
+var (
+	ErrInvalidAttribute       = errors.New("invalid attribute")
+	ErrInvalidAttributeFilter = errors.New("invalid attribute filter")
+
+	attributeCodePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+)
+
+type AttributeService interface {
+	CreateAttribute(ctx context.Context, req *models.CreateAttributeRequest) (*models.Attribute, error)
+	ListAttributes(ctx context.Context) ([]*models.Attribute, error)
+	DeleteAttribute(ctx context.Context, id int64) error
+	SetProductAttributes(ctx context.Context, productID int64, req *models.SetProductAttributesRequest) error
+	Facets(ctx context.Context, params *models.ProductListParams) (*models.FacetsResponse, error)
+}
+
+type attributeService struct {
+	productRepo   repositories.ProductRepository
+	attributeRepo repositories.AttributeRepository
+}
+
+func NewAttributeService(productRepo repositories.ProductRepository, attributeRepo repositories.AttributeRepository) AttributeService {
+	return &attributeService{
+		productRepo:   productRepo,
+		attributeRepo: attributeRepo,
+	}
+}
+
+func (s *attributeService) CreateAttribute(ctx context.Context, req *models.CreateAttributeRequest) (*models.Attribute, error) {
+	if !attributeCodePattern.MatchString(req.Code) {
+		return nil, fmt.Errorf("%w: code must contain only lowercase latin letters, digits and underscores", ErrInvalidAttribute)
+	}
+
+	attr := &models.Attribute{
+		Code:       req.Code,
+		Name:       req.Name,
+		Type:       req.Type,
+		Options:    []string{},
+		Filterable: true,
+		Position:   req.Position,
+	}
+	if req.Filterable != nil {
+		attr.Filterable = *req.Filterable
+	}
+
+	if req.Type == models.AttributeEnum {
+		if len(req.Options) == 0 {
+			return nil, fmt.Errorf("%w: enum attribute requires options", ErrInvalidAttribute)
+		}
+		attr.Options = req.Options
+	} else if len(req.Options) > 0 {
+		return nil, fmt.Errorf("%w: options are allowed only for enum attributes", ErrInvalidAttribute)
+	}
+
+	if err := s.attributeRepo.Create(ctx, attr); err != nil {
+		return nil, err
+	}
+
+	return attr, nil
+}
+
+func (s *attributeService) ListAttributes(ctx context.Context) ([]*models.Attribute, error) {
+	return s.attributeRepo.List(ctx)
+}
+
+func (s *attributeService) DeleteAttribute(ctx context.Context, id int64) error {
+	if err := s.attributeRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.productRepo.InvalidateCache(ctx)
+	return nil
+}
+
+// SetProductAttributes заменяет атрибуты товара целиком. null в значении
+// убирает атрибут.
+func (s *attributeService) SetProductAttributes(ctx context.Context, productID int64, req *models.SetProductAttributesRequest) error {
+	list, err := s.attributeRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+	defs := attributeDefinitions(list)
+
+	attributes := make(map[string]any, len(req.Attributes))
+	for code, value := range req.Attributes {
+		if value == nil {
+			continue
+		}
+
+		def, ok := defs[code]
+		if !ok {
+			return fmt.Errorf("%w: unknown attribute %q", ErrInvalidAttribute, code)
+		}
+		if !attributeValueValid(def, value) {
+			return fmt.Errorf("%w: %q expects %s value", ErrInvalidAttribute, code, def.Type)
+		}
+		attributes[code] = value
+	}
+
+	return s.productRepo.SetAttributes(ctx, productID, attributes)
+}
+
+// Facets считает значения фильтруемых атрибутов для текущих фильтров.
+// Атрибут, по которому уже есть фильтр, считается без него, иначе в фасете
+// осталось бы только выбранное значение.
+func (s *attributeService) Facets(ctx context.Context, params *models.ProductListParams) (*models.FacetsResponse, error) {
+	attributes, err := s.attributeRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := resolveAttributeFilters(attributeDefinitions(attributes), params.Attributes); err != nil {
+		return nil, err
+	}
+
+	filtered := make(map[string]bool, len(params.Attributes))
+	for _, f := range params.Attributes {
+		filtered[f.Code] = true
+	}
+
+	var unfiltered []string
+	for _, a := range attributes {
+		if a.Filterable && !filtered[a.Code] {
+			unfiltered = append(unfiltered, a.Code)
+		}
+	}
+
+	counts, err := s.productRepo.FacetCounts(ctx, params, unfiltered, "")
+	if err != nil {
+		return nil, err
+	}
+
+	for code := range filtered {
+		own, err := s.productRepo.FacetCounts(ctx, params, []string{code}, code)
+		if err != nil {
+			return nil, err
+		}
+		counts[code] = own[code]
+	}
+
+	response := &models.FacetsResponse{Facets: make([]*models.Facet, 0)}
+	for _, a := range attributes {
+		values, ok := counts[a.Code]
+		if !ok {
+			continue
+		}
+
+		if a.Type == models.AttributeNumber {
+			sort.SliceStable(values, func(i, j int) bool {
+				vi, _ := strconv.ParseFloat(values[i].Value, 64)
+				vj, _ := strconv.ParseFloat(values[j].Value, 64)
+				return vi < vj
+			})
+		}
+
+		response.Facets = append(response.Facets, &models.Facet{
+			Code:   a.Code,
+			Name:   a.Name,
+			Type:   a.Type,
+			Values: values,
+		})
+	}
+
+	return response, nil
+}
+
+func attributeDefinitions(attributes []*models.Attribute) map[string]*models.Attribute {
+	defs := make(map[string]*models.Attribute, len(attributes))
+	for _, a := range attributes {
+		defs[a.Code] = a
+	}
+	return defs
+}
+
+// attributeValueValid проверяет значение из JSON: числа приходят как float64.
+func attributeValueValid(def *models.Attribute, value any) bool {
+	switch def.Type {
+	case models.AttributeString:
+		_, ok := value.(string)
+		return ok
+	case models.AttributeNumber:
+		_, ok := value.(float64)
+		return ok
+	case models.AttributeBoolean:
+		_, ok := value.(bool)
+		return ok
+	case models.AttributeEnum:
+		v, ok := value.(string)
+		return ok && slices.Contains(def.Options, v)
+	}
+	return false
+}
+
+// resolveAttributeFilters проставляет фильтрам тип атрибута и проверяет,
+// что значения ему соответствуют, до того как они попадут в SQL.
+func resolveAttributeFilters(defs map[string]*models.Attribute, filters []models.AttributeFilter) error {
+	for i := range filters {
+		f := &filters[i]
+
+		def, ok := defs[f.Code]
+		if !ok || !def.Filterable {
+			return fmt.Errorf("%w: unknown attribute %q", ErrInvalidAttributeFilter, f.Code)
+		}
+		f.Type = def.Type
+
+		if f.Op != "eq" {
+			if def.Type != models.AttributeNumber {
+				return fmt.Errorf("%w: %s is supported only for number attributes", ErrInvalidAttributeFilter, f.Op)
+			}
+			if len(f.Values) != 1 {
+				return fmt.Errorf("%w: %s expects a single value", ErrInvalidAttributeFilter, f.Op)
+			}
+		}
+
+		for _, v := range f.Values {
+			var err error
+			switch def.Type {
+			case models.AttributeNumber:
+				_, err = strconv.ParseFloat(v, 64)
+			case models.AttributeBoolean:
+				_, err = strconv.ParseBool(v)
+			case models.AttributeEnum:
+				if !slices.Contains(def.Options, v) {
+					err = errors.New("not an option")
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("%w: invalid value %q for %q", ErrInvalidAttributeFilter, v, f.Code)
+			}
+		}
+	}
+
+	return nil
+}
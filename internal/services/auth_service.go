@@ -13,7 +13,7 @@ import (
 
 type AuthService interface {
 	Register(ctx context.Context, req *models.RegisterRequest) (int64, error)
-	Login(ctx context.Context, req *models.LoginRequest) (string, error)
+	Login(ctx context.Context, req *models.LoginRequest) (token string, userID int64, err error)
 	ValidateToken(tokenString string) (int64, error)
 }
 
@@ -37,10 +37,10 @@ func (s *authService) Register(ctx context.Context, req *models.RegisterRequest)
 	return s.userRepo.Create(ctx, req.Email, hash)
 }
 
-func (s *authService) Login(ctx context.Context, req *models.LoginRequest) (string, error) {
+func (s *authService) Login(ctx context.Context, req *models.LoginRequest) (string, int64, error) {
 	user, err := s.userRepo.GetByEmail(ctx, req.Email)
 	if err != nil || !repositories.CheckPasswordHash(req.Password, user.PasswordHash) {
-		return "", fmt.Errorf("invalid credentials")
+		return "", 0, fmt.Errorf("invalid credentials")
 	}
 
 	exp := time.Now().Add(s.jwtCfg.Expires)
@@ -49,7 +49,11 @@ func (s *authService) Login(ctx context.Context, req *models.LoginRequest) (stri
 		"exp":     exp.Unix(),
 	})
 
-	return token.SignedString([]byte(s.jwtCfg.Secret))
+	signed, err := token.SignedString([]byte(s.jwtCfg.Secret))
+	if err != nil {
+		return "", 0, err
+	}
+	return signed, user.ID, nil
 }
 
 func (s *authService) ValidateToken(tokenString string) (int64, error) {
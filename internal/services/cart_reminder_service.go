@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"ecommerce-api/internal/config"
+	"ecommerce-api/internal/models"
+	"ecommerce-api/internal/notify"
+	"ecommerce-api/internal/repositories"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe token")
+
+// maxReminderItems — сколько позиций корзины перечислять в письме.
+const maxReminderItems = 10
+
+type CartReminderService interface {
+	SetRemindersEnabled(ctx context.Context, userID int64, enabled bool) error
+	// Unsubscribe отключает напоминания по ссылке из письма, без входа.
+	Unsubscribe(ctx context.Context, token string) error
+	RunReminders(ctx context.Context, interval time.Duration)
+}
+
+type cartReminderService struct {
+	activityRepo repositories.CartActivityRepository
+	cartRepo     repositories.CartRepository
+	productRepo  repositories.ProductRepository
+	variantRepo  repositories.VariantRepository
+	notifier     notify.Notifier
+	cfg          config.CartReminderConfig
+}
+
+func NewCartReminderService(
+	activityRepo repositories.CartActivityRepository,
+	cartRepo repositories.CartRepository,
+	productRepo repositories.ProductRepository,
+	variantRepo repositories.VariantRepository,
+	notifier notify.Notifier,
+	cfg config.CartReminderConfig,
+) CartReminderService {
+	return &cartReminderService{
+		activityRepo: activityRepo,
+		cartRepo:     cartRepo,
+		productRepo:  productRepo,
+		variantRepo:  variantRepo,
+		notifier:     notifier,
+		cfg:          cfg,
+	}
+}
+
+func (s *cartReminderService) SetRemindersEnabled(ctx context.Context, userID int64, enabled bool) error {
+	return s.activityRepo.SetRemindersEnabled(ctx, userID, enabled)
+}
+
+func (s *cartReminderService) Unsubscribe(ctx context.Context, token string) error {
+	userIDPart, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.signUserID(userIDPart))) {
+		return ErrInvalidUnsubscribeToken
+	}
+	userID, err := strconv.ParseInt(userIDPart, 10, 64)
+	if err != nil {
+		return ErrInvalidUnsubscribeToken
+	}
+	return s.activityRepo.SetRemindersEnabled(ctx, userID, false)
+}
+
+// Префикс не даёт выдать подпись токена гостевой корзины за подпись отписки:
+// секрет у них может быть общий.
+func (s *cartReminderService) signUserID(userID string) string {
+	mac := hmac.New(sha256.New, []byte(s.cfg.TokenSecret))
+	mac.Write([]byte("cart-reminders:" + userID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *cartReminderService) unsubscribeURL(userID int64) string {
+	id := strconv.FormatInt(userID, 10)
+	query := url.Values{"token": {id + "." + s.signUserID(id)}}
+
+	sep := "?"
+	if strings.Contains(s.cfg.UnsubscribeURL, "?") {
+		sep = "&"
+	}
+	return s.cfg.UnsubscribeURL + sep + query.Encode()
+}
+
+// RunReminders раз в interval рассылает напоминания о брошенных корзинах,
+// не больше cfg.BatchSize писем за проход.
+func (s *cartReminderService) RunReminders(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		sent, err := s.sendReminders(ctx)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			log.Printf("cart reminders error: %v", err)
+		case sent > 0:
+			log.Printf("cart reminders: sent %d reminders", sent)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *cartReminderService) sendReminders(ctx context.Context) (int, error) {
+	if _, err := s.activityRepo.DeleteExpired(ctx); err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	carts, err := s.activityRepo.ClaimIdle(ctx, now.Add(-s.cfg.IdleAfter), now.Add(-s.cfg.MinInterval), s.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var sent int
+	for i, cart := range carts {
+		if ctx.Err() != nil {
+			// Захваченные, но не обработанные корзины достанутся следующему проходу.
+			for _, cart := range carts[i:] {
+				s.release(ctx, cart)
+			}
+			return sent, ctx.Err()
+		}
+
+		// Ошибка одной корзины не должна останавливать рассылку остальным;
+		// неотправленное напоминание повторится на следующем проходе.
+		ok, err := s.remind(ctx, cart)
+		if err != nil {
+			log.Printf("cart reminder for user %d failed: %v", cart.UserID, err)
+			s.release(ctx, cart)
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// release работает и после отмены ctx: при остановке сервиса захват
+// тоже нужно снять.
+func (s *cartReminderService) release(ctx context.Context, cart *models.IdleCart) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	if err := s.activityRepo.Release(ctx, cart); err != nil {
+		log.Printf("cart reminder for user %d: failed to release claim: %v", cart.UserID, err)
+	}
+}
+
+func (s *cartReminderService) remind(ctx context.Context, cart *models.IdleCart) (bool, error) {
+	lines, _, err := s.cartRepo.GetCart(ctx, models.UserCart(cart.UserID))
+	if err != nil {
+		return false, err
+	}
+	if len(lines) == 0 {
+		return false, s.activityRepo.Delete(ctx, cart.UserID)
+	}
+
+	body, listed, err := s.reminderBody(ctx, cart.UserID, lines)
+	if err != nil {
+		return false, err
+	}
+	// Все товары корзины сняты с продажи: письмо не отправляем, а корзина
+	// остаётся отмеченной, чтобы не перебирать её на каждом проходе.
+	if listed == 0 {
+		return false, nil
+	}
+
+	err = s.notifier.Send(ctx, notify.Message{
+		To:      cart.Email,
+		Subject: "You left items in your cart",
+		Body:    body,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *cartReminderService) reminderBody(ctx context.Context, userID int64, lines map[models.CartItemKey]models.CartLine) (string, int, error) {
+	productMap, variantMap, err := loadCartItems(ctx, s.productRepo, s.variantRepo, lines)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var b strings.Builder
+	b.WriteString("You still have items waiting in your cart:\n\n")
+
+	var listed int
+	for item, line := range lines {
+		product, ok := productMap[item.ProductID]
+		if !ok {
+			continue
+		}
+		if listed == maxReminderItems {
+			b.WriteString("- ...and more\n")
+			break
+		}
+
+		name, price := product.Name, product.Price
+		if item.VariantID != 0 {
+			variant, ok := variantMap[item.VariantID]
+			if !ok || variant.ProductID != product.ID {
+				continue
+			}
+			name = fmt.Sprintf("%s (%s)", product.Name, variant.SKU)
+			price = variant.PriceFor(product)
+		}
+
+		fmt.Fprintf(&b, "- %s x %d: %.2f\n", name, line.Quantity, price)
+		listed++
+	}
+
+	fmt.Fprintf(&b, "\nComplete your order: %s\n", s.cfg.CartURL)
+	fmt.Fprintf(&b, "\nTo stop receiving cart reminders, follow this link: %s\n", s.unsubscribeURL(userID))
+	return b.String(), listed, nil
+}
@@ -1,101 +1,629 @@
 package services
 
 import (
+	"cmp"
 	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"ecommerce-api/internal/config"
 	"ecommerce-api/internal/models"
 	"ecommerce-api/internal/repositories"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrProductNotFound  = errors.New("product not found")
+	ErrVariantNotFound  = errors.New("variant not found")
+	ErrVariantRequired  = errors.New("product has variants, variant_id is required")
+	ErrInvalidCartToken = errors.New("invalid cart token")
+	ErrOutOfStock       = errors.New("product is out of stock")
+	ErrCartEmpty        = errors.New("cart is empty")
+	// ErrCartSharingUnavailable — ссылки на корзины хранятся в Redis, без него делиться нельзя.
+	ErrCartSharingUnavailable = errors.New("cart sharing is temporarily unavailable")
 )
 
 type CartService interface {
-	AddItem(ctx context.Context, userID int64, productID int64, quantity int) error
-	UpdateItem(ctx context.Context, userID int64, productID int64, quantity int) error
-	RemoveItem(ctx context.Context, userID int64, productID int64) error
-	GetCartResponse(ctx context.Context, userID int64) (*models.CartResponse, error)
-	ClearCart(ctx context.Context, userID int64) error
+	// AddItem и UpdateItem не дают положить больше, чем есть на складе,
+	// и возвращают итоговое количество позиции; capped — если его пришлось урезать.
+	// Нарушение правил покупки товара возвращается как *PurchaseRuleError.
+	AddItem(ctx context.Context, owner models.CartOwner, item models.CartItemKey, quantity int) (total int, capped bool, err error)
+	UpdateItem(ctx context.Context, owner models.CartOwner, item models.CartItemKey, quantity int) (total int, capped bool, err error)
+	RemoveItem(ctx context.Context, owner models.CartOwner, item models.CartItemKey) error
+	GetCartResponse(ctx context.Context, owner models.CartOwner, recommendations int) (*models.CartResponse, error)
+	ClearCart(ctx context.Context, owner models.CartOwner) error
+	IssueGuestToken() (token string, guestID string, err error)
+	ParseGuestToken(token string) (string, error)
+	MergeGuestCart(ctx context.Context, token string, userID int64) error
+	// ShareCart сохраняет копию корзины и возвращает токен ссылки на неё.
+	ShareCart(ctx context.Context, owner models.CartOwner) (*models.CartShareResponse, error)
+	// ImportCart добавляет позиции из копии в корзину owner по тем же
+	// правилам, что и AddItem: с проверкой товаров и остатков. Количество сверх
+	// max_quantity и customer_limit не отклоняется, а урезается до допустимого.
+	ImportCart(ctx context.Context, owner models.CartOwner, token string) (*models.CartImportResponse, error)
 }
 
 type cartService struct {
-	cartRepo    repositories.CartRepository
-	productRepo repositories.ProductRepository
+	cartRepo     repositories.CartRepository
+	activityRepo repositories.CartActivityRepository
+	shareRepo    repositories.CartShareRepository
+	ruleRepo     repositories.PurchaseRuleRepository
+	productRepo  repositories.ProductRepository
+	variantRepo  repositories.VariantRepository
+	imageSvc     ImageService
+	recSvc       RecommendationService
+	cfg          config.CartConfig
 }
 
-func NewCartService(cartRepo repositories.CartRepository, productRepo repositories.ProductRepository) CartService {
+func NewCartService(
+	cartRepo repositories.CartRepository,
+	activityRepo repositories.CartActivityRepository,
+	shareRepo repositories.CartShareRepository,
+	ruleRepo repositories.PurchaseRuleRepository,
+	productRepo repositories.ProductRepository,
+	variantRepo repositories.VariantRepository,
+	imageSvc ImageService,
+	recSvc RecommendationService,
+	cfg config.CartConfig,
+) CartService {
 	return &cartService{
-		cartRepo:    cartRepo,
-		productRepo: productRepo,
+		cartRepo:     cartRepo,
+		activityRepo: activityRepo,
+		shareRepo:    shareRepo,
+		ruleRepo:     ruleRepo,
+		productRepo:  productRepo,
+		variantRepo:  variantRepo,
+		imageSvc:     imageSvc,
+		recSvc:       recSvc,
+		cfg:          cfg,
+	}
+}
+
+// quantityCap — причина, по которой количество позиции урезано.
+type quantityCap struct {
+	// Code — код предупреждения корзины или нарушенного правила покупки.
+	Code    string
+	Message string
+}
+
+var stockCap = &quantityCap{Code: models.CartWarningInsufficientStock, Message: "not enough stock"}
+
+func (cs *cartService) AddItem(ctx context.Context, owner models.CartOwner, item models.CartItemKey, quantity int) (int, bool, error) {
+	total, _, capped, err := cs.addItem(ctx, owner, item, quantity, false)
+	if err != nil {
+		return 0, false, err
+	}
+	return total, capped != nil, nil
+}
+
+// addItem добавляет позицию и возвращает её итоговое количество, сколько
+// штук действительно добавлено и почему количество урезано, если урезано.
+// С capToRules превышение max_quantity и customer_limit урезается, а не
+// возвращается ошибкой.
+func (cs *cartService) addItem(
+	ctx context.Context,
+	owner models.CartOwner,
+	item models.CartItemKey,
+	quantity int,
+	capToRules bool,
+) (int, int, *quantityCap, error) {
+	price, inventory, err := resolveItem(ctx, cs.productRepo, cs.variantRepo, item)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	if inventory <= 0 {
+		return 0, 0, nil, ErrOutOfStock
+	}
+
+	cart, _, err := cs.cartRepo.GetCart(ctx, owner)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	current := cart[item].Quantity
+
+	total, capped, err := cs.allowedQuantity(ctx, owner, item, cart, current+quantity, inventory, capToRules)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	if total <= current {
+		return current, 0, capped, nil
+	}
+
+	if err := cs.cartRepo.AddItem(ctx, owner, item, total-current, price); err != nil {
+		return 0, 0, nil, err
+	}
+	cs.touchActivity(ctx, owner)
+	return total, total - current, capped, nil
+}
+
+func (cs *cartService) UpdateItem(ctx context.Context, owner models.CartOwner, item models.CartItemKey, quantity int) (int, bool, error) {
+	if quantity <= 0 {
+		return 0, false, cs.RemoveItem(ctx, owner, item)
+	}
+
+	price, inventory, err := resolveItem(ctx, cs.productRepo, cs.variantRepo, item)
+	if err != nil {
+		return 0, false, err
+	}
+	if inventory <= 0 {
+		return 0, false, ErrOutOfStock
+	}
+
+	cart, _, err := cs.cartRepo.GetCart(ctx, owner)
+	if err != nil {
+		return 0, false, err
+	}
+
+	total, capped, err := cs.allowedQuantity(ctx, owner, item, cart, quantity, inventory, false)
+	if err != nil {
+		return 0, false, err
+	}
+	if err := cs.cartRepo.UpdateItem(ctx, owner, item, total, price); err != nil {
+		return 0, false, err
+	}
+	cs.touchActivity(ctx, owner)
+	return total, capped != nil, nil
+}
+
+// allowedQuantity проверяет желаемое количество позиции по правилам покупки
+// товара и урезает его до остатка на складе, сохраняя кратность упаковке.
+// Вторым значением возвращается причина, если количество урезано.
+func (cs *cartService) allowedQuantity(
+	ctx context.Context,
+	owner models.CartOwner,
+	item models.CartItemKey,
+	cart map[models.CartItemKey]models.CartLine,
+	quantity int,
+	inventory int,
+	capToRules bool,
+) (int, *quantityCap, error) {
+	rules, err := cs.ruleRepo.Get(ctx, item.ProductID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var capped *quantityCap
+	others := otherVariantsQuantity(cart, item)
+	err = checkPurchaseRules(ctx, cs.ruleRepo, rules, owner.UserID, item, quantity, others)
+	var ruleErr *PurchaseRuleError
+	if capToRules && errors.As(err, &ruleErr) && ruleErr.Allowed != nil {
+		quantity = *ruleErr.Allowed - *ruleErr.Allowed%rules.QuantityStep
+		capped = &quantityCap{Code: ruleErr.Code, Message: ruleErr.Message}
+		// Если и урезанное количество не проходит (например, меньше
+		// min_quantity), причина отказа — всё равно исходный лимит.
+		recheck := checkPurchaseRules(ctx, cs.ruleRepo, rules, owner.UserID, item, quantity, others)
+		if recheck == nil || !errors.As(recheck, new(*PurchaseRuleError)) {
+			err = recheck
+		}
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	total := quantity
+	if inventory < total {
+		total = inventory
+		capped = stockCap
+	}
+	if rules != nil {
+		total -= total % rules.QuantityStep
+		if total < rules.MinQuantity {
+			return 0, nil, fmt.Errorf("%w: not enough stock for the minimum order quantity of %d", ErrOutOfStock, rules.MinQuantity)
+		}
+	}
+	return total, capped, nil
+}
+
+func (cs *cartService) RemoveItem(ctx context.Context, owner models.CartOwner, item models.CartItemKey) error {
+	if err := cs.cartRepo.RemoveItem(ctx, owner, item); err != nil {
+		return err
+	}
+	cs.touchActivity(ctx, owner)
+	return nil
+}
+
+func (cs *cartService) ClearCart(ctx context.Context, owner models.CartOwner) error {
+	if err := cs.cartRepo.ClearCart(ctx, owner); err != nil {
+		return err
+	}
+	// О пустой корзине напоминать нечего.
+	if !owner.IsGuest() {
+		if err := cs.activityRepo.Delete(ctx, owner.UserID); err != nil {
+			log.Printf("cart activity error for user %d: %v", owner.UserID, err)
+		}
 	}
+	return nil
 }
 
-func (cs *cartService) AddItem(ctx context.Context, userID int64, productID int64, quantity int) error {
-	return cs.cartRepo.AddItem(ctx, userID, productID, quantity)
+// touchActivity отмечает изменение корзины пользователя для напоминаний о
+// брошенных корзинах. Ошибка только логируется: из-за неё не должно
+// срываться само изменение корзины.
+func (cs *cartService) touchActivity(ctx context.Context, owner models.CartOwner) {
+	if owner.IsGuest() {
+		return
+	}
+	if err := cs.activityRepo.Touch(ctx, owner.UserID); err != nil {
+		log.Printf("cart activity error for user %d: %v", owner.UserID, err)
+	}
 }
 
-func (cs *cartService) UpdateItem(ctx context.Context, userID int64, productID int64, quantity int) error {
-	return cs.cartRepo.UpdateItem(ctx, userID, productID, quantity)
+// Токен гостевой корзины — "<guestID>.<hmac>": без подписи любой мог бы
+// подобрать чужой guestID и читать или менять его корзину.
+func (cs *cartService) IssueGuestToken() (string, string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	guestID := hex.EncodeToString(buf)
+	return guestID + "." + cs.signGuestID(guestID), guestID, nil
 }
 
-func (cs *cartService) RemoveItem(ctx context.Context, userID int64, productID int64) error {
-	return cs.cartRepo.RemoveItem(ctx, userID, productID)
+func (cs *cartService) ParseGuestToken(token string) (string, error) {
+	guestID, signature, ok := strings.Cut(token, ".")
+	if !ok || len(guestID) != 32 || !hmac.Equal([]byte(signature), []byte(cs.signGuestID(guestID))) {
+		return "", ErrInvalidCartToken
+	}
+	return guestID, nil
 }
 
-func (cs *cartService) ClearCart(ctx context.Context, userID int64) error {
-	return cs.cartRepo.ClearCart(ctx, userID)
+func (cs *cartService) signGuestID(guestID string) string {
+	mac := hmac.New(sha256.New, []byte(cs.cfg.TokenSecret))
+	mac.Write([]byte(guestID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// MergeGuestCart переносит гостевую корзину пользователю по стратегии
+// из конфигурации.
+func (cs *cartService) MergeGuestCart(ctx context.Context, token string, userID int64) error {
+	guestID, err := cs.ParseGuestToken(token)
+	if err != nil {
+		return err
+	}
+	moved, err := cs.cartRepo.MergeGuestCart(ctx, guestID, userID, cs.cfg.MergeStrategy)
+	if err != nil {
+		return err
+	}
+	if moved > 0 {
+		cs.touchActivity(ctx, models.UserCart(userID))
+	}
+	return nil
 }
 
-func (cs *cartService) GetCartResponse(ctx context.Context, userID int64) (*models.CartResponse, error) {
-	cartMap, err := cs.cartRepo.GetCart(ctx, userID)
+func (cs *cartService) ShareCart(ctx context.Context, owner models.CartOwner) (*models.CartShareResponse, error) {
+	if cs.shareRepo == nil {
+		return nil, ErrCartSharingUnavailable
+	}
+
+	cart, _, err := cs.cartRepo.GetCart(ctx, owner)
 	if err != nil {
 		return nil, err
 	}
+	if len(cart) == 0 {
+		return nil, ErrCartEmpty
+	}
 
-	if len(cartMap) == 0 {
-		return &models.CartResponse{}, nil
+	items := make([]models.SharedCartItem, 0, len(cart))
+	for item, line := range cart {
+		items = append(items, models.SharedCartItem{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  line.Quantity,
+		})
 	}
+	slices.SortFunc(items, func(a, b models.SharedCartItem) int {
+		return cmp.Or(cmp.Compare(a.ProductID, b.ProductID), cmp.Compare(a.VariantID, b.VariantID))
+	})
 
-	productIDs := make([]int64, 0, len(cartMap))
-	for id := range cartMap {
-		productIDs = append(productIDs, id)
+	// 12 байт дают 16 символов токена — коротко для ссылки и не подобрать.
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	if err := cs.shareRepo.Save(ctx, token, items, cs.cfg.ShareTTL); err != nil {
+		return nil, err
 	}
 
-	products, err := cs.productRepo.GetByIDs(ctx, productIDs)
+	return &models.CartShareResponse{Token: token, ExpiresAt: time.Now().Add(cs.cfg.ShareTTL)}, nil
+}
+
+func (cs *cartService) ImportCart(ctx context.Context, owner models.CartOwner, token string) (*models.CartImportResponse, error) {
+	if cs.shareRepo == nil {
+		return nil, ErrCartSharingUnavailable
+	}
+
+	items, err := cs.shareRepo.Get(ctx, token)
 	if err != nil {
 		return nil, err
 	}
 
-	productMap := make(map[int64]*models.Product)
-	for _, p := range products {
-		productMap[p.ID] = p
+	response := &models.CartImportResponse{Warnings: make([]models.CartWarning, 0)}
+	for _, shared := range items {
+		item := models.CartItemKey{ProductID: shared.ProductID, VariantID: shared.VariantID}
+		warning := models.CartWarning{ProductID: item.ProductID}
+		if item.VariantID != 0 {
+			warning.VariantID = &item.VariantID
+		}
+
+		total, added, capped, err := cs.addItem(ctx, owner, item, shared.Quantity, true)
+		var ruleErr *PurchaseRuleError
+		switch {
+		case errors.As(err, &ruleErr):
+			warning.Code = ruleErr.Code
+			warning.Message = ruleErr.Message
+		case errors.Is(err, ErrProductNotFound), errors.Is(err, ErrVariantNotFound), errors.Is(err, ErrVariantRequired):
+			warning.Code = models.CartWarningProductRemoved
+			warning.Message = "product is no longer available"
+		case errors.Is(err, ErrOutOfStock):
+			warning.Code = models.CartWarningOutOfStock
+			warning.Message = "product is out of stock"
+		case err != nil:
+			return nil, err
+		case capped != nil:
+			// Позиция, которая уже была в корзине на пределе, не добавилась.
+			if added > 0 {
+				response.Imported++
+			}
+			warning.Code = capped.Code
+			warning.Message = fmt.Sprintf("only %d items are in the cart: %s", total, capped.Message)
+			warning.Available = total
+		default:
+			response.Imported++
+			continue
+		}
+		response.Warnings = append(response.Warnings, warning)
+	}
+
+	return response, nil
+}
+
+// resolveItem проверяет, что товар существует и что вариант указан ровно
+// тогда, когда у товара есть варианты, и возвращает текущие цену и остаток позиции.
+func resolveItem(
+	ctx context.Context,
+	productRepo repositories.ProductRepository,
+	variantRepo repositories.VariantRepository,
+	item models.CartItemKey,
+) (float64, int, error) {
+	product, err := productRepo.GetByID(ctx, item.ProductID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, 0, fmt.Errorf("%w: %d", ErrProductNotFound, item.ProductID)
+		}
+		return 0, 0, err
+	}
+
+	variants, err := variantRepo.ListByProduct(ctx, item.ProductID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if item.VariantID == 0 {
+		if len(variants) > 0 {
+			return 0, 0, ErrVariantRequired
+		}
+		return product.Price, product.Inventory, nil
+	}
+
+	for _, v := range variants {
+		if v.ID == item.VariantID {
+			return v.PriceFor(product), v.Inventory, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("%w: %d", ErrVariantNotFound, item.VariantID)
+}
+
+// GetCartResponse при recommendations > 0 добавляет до recommendations
+// товаров, которые рекомендуются к содержимому корзины.
+func (cs *cartService) GetCartResponse(ctx context.Context, owner models.CartOwner, recommendations int) (*models.CartResponse, error) {
+	cartMap, version, err := cs.cartRepo.GetCart(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cartMap) == 0 {
+		return &models.CartResponse{
+			Items:    []models.CartResponseItem{},
+			Warnings: []models.CartWarning{},
+			Version:  version,
+		}, nil
+	}
+
+	productMap, variantMap, err := loadCartItems(ctx, cs.productRepo, cs.variantRepo, cartMap)
+	if err != nil {
+		return nil, err
+	}
+
+	productIDs := make([]int64, 0, len(productMap))
+	for id := range productMap {
+		productIDs = append(productIDs, id)
+	}
+
+	imageURLs, err := cs.imageSvc.MainImageURLs(ctx, productIDs)
+	if err != nil {
+		return nil, err
 	}
 
 	response := &models.CartResponse{
-		Items: make([]models.CartResponseItem, 0, len(cartMap)),
+		Items:    make([]models.CartResponseItem, 0, len(cartMap)),
+		Warnings: make([]models.CartWarning, 0),
+		Version:  version,
 	}
 
 	var total float64
 	var itemCount int
 
-	for productID, quantity := range cartMap {
-		product, found := productMap[productID]
+	for item, line := range cartMap {
+		quantity := line.Quantity
+		warning := models.CartWarning{ProductID: item.ProductID}
+		if item.VariantID != 0 {
+			warning.VariantID = &item.VariantID
+		}
+
+		product, found := productMap[item.ProductID]
 		if !found {
+			warning.Code = models.CartWarningProductRemoved
+			warning.Message = "product is no longer available"
+			response.Warnings = append(response.Warnings, warning)
 			continue
 		}
 
-		subtotal := float64(quantity) * product.Price
-		total += subtotal
-		itemCount += quantity
-
-		response.Items = append(response.Items, models.CartResponseItem{
+		responseItem := models.CartResponseItem{
 			ProductID:   product.ID,
 			Name:        product.Name,
 			Description: product.Description,
+			ImageURL:    imageURLs[product.ID],
 			Price:       product.Price,
 			Quantity:    quantity,
-			Subtotal:    subtotal,
-		})
+		}
+
+		inventory := product.Inventory
+		if item.VariantID != 0 {
+			variant, found := variantMap[item.VariantID]
+			if !found || variant.ProductID != product.ID {
+				warning.Code = models.CartWarningProductRemoved
+				warning.Message = "variant is no longer available"
+				response.Warnings = append(response.Warnings, warning)
+				continue
+			}
+			responseItem.VariantID = &variant.ID
+			responseItem.SKU = variant.SKU
+			responseItem.Options = variant.Options
+			responseItem.Price = variant.PriceFor(product)
+			inventory = variant.Inventory
+		}
+
+		switch {
+		case inventory <= 0:
+			warning.Code = models.CartWarningOutOfStock
+			warning.Message = "product is out of stock"
+			response.Warnings = append(response.Warnings, warning)
+		case quantity > inventory:
+			warning.Code = models.CartWarningInsufficientStock
+			warning.Message = fmt.Sprintf("only %d items left in stock", inventory)
+			warning.Available = inventory
+			response.Warnings = append(response.Warnings, warning)
+		}
+
+		if priceChanged(line.Price, responseItem.Price) {
+			responseItem.AddedPrice = line.Price
+			responseItem.PriceChanged = true
+
+			priceWarning := warning
+			priceWarning.Code = models.CartWarningPriceChanged
+			priceWarning.Message = "price has changed since the product was added to the cart"
+			priceWarning.Available = 0
+			priceWarning.OldPrice = line.Price
+			priceWarning.NewPrice = responseItem.Price
+			response.Warnings = append(response.Warnings, priceWarning)
+		}
+
+		responseItem.Subtotal = float64(quantity) * responseItem.Price
+		total += responseItem.Subtotal
+		itemCount += quantity
+
+		response.Items = append(response.Items, responseItem)
 	}
 
 	response.Total = total
 	response.ItemCount = itemCount
 
+	lines := make([]checksumLine, len(response.Items))
+	for i, it := range response.Items {
+		lines[i] = checksumLine{ProductID: it.ProductID, Quantity: it.Quantity, Price: it.Price}
+		if it.VariantID != nil {
+			lines[i].VariantID = *it.VariantID
+		}
+	}
+	response.Checksum = cartChecksum(lines)
+
+	if recommendations > 0 {
+		response.Recommendations, err = cs.recSvc.ForProducts(ctx, productIDs, min(recommendations, maxCartRecommendations))
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return response, nil
 }
+
+// priceChanged сравнивает цены с точностью до копейки. Нулевая старая цена
+// означает, что снимка цены у позиции нет.
+func priceChanged(old, current float64) bool {
+	return old != 0 && math.Round(old*100) != math.Round(current*100)
+}
+
+type checksumLine struct {
+	ProductID int64
+	VariantID int64
+	Quantity  int
+	Price     float64
+}
+
+// cartChecksum не зависит от порядка позиций, поэтому корзина и заказ
+// получают одинаковую сумму для одного и того же содержимого.
+func cartChecksum(lines []checksumLine) string {
+	slices.SortFunc(lines, func(a, b checksumLine) int {
+		return cmp.Or(cmp.Compare(a.ProductID, b.ProductID), cmp.Compare(a.VariantID, b.VariantID))
+	})
+
+	h := sha256.New()
+	for _, l := range lines {
+		fmt.Fprintf(h, "%d:%d:%d:%.2f\n", l.ProductID, l.VariantID, l.Quantity, l.Price)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// loadCartItems одним запросом на таблицу подгружает товары и варианты из корзины.
+func loadCartItems(
+	ctx context.Context,
+	productRepo repositories.ProductRepository,
+	variantRepo repositories.VariantRepository,
+	cartMap map[models.CartItemKey]models.CartLine,
+) (map[int64]*models.Product, map[int64]*models.ProductVariant, error) {
+	productIDs := make([]int64, 0, len(cartMap))
+	variantIDs := make([]int64, 0)
+	seen := make(map[int64]bool, len(cartMap))
+	for item := range cartMap {
+		if !seen[item.ProductID] {
+			seen[item.ProductID] = true
+			productIDs = append(productIDs, item.ProductID)
+		}
+		if item.VariantID != 0 {
+			variantIDs = append(variantIDs, item.VariantID)
+		}
+	}
+
+	products, err := productRepo.GetByIDs(ctx, productIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	variants, err := variantRepo.GetByIDs(ctx, variantIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	productMap := make(map[int64]*models.Product, len(products))
+	for _, p := range products {
+		productMap[p.ID] = p
+	}
+
+	variantMap := make(map[int64]*models.ProductVariant, len(variants))
+	for _, v := range variants {
+		variantMap[v.ID] = v
+	}
+
+	return productMap, variantMap, nil
+}
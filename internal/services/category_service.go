@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"ecommerce-api/internal/models"
+	"ecommerce-api/internal/repositories"
+)
+
+const defaultCategoryProductsPageSize = 20
+
+type CategoryService interface {
+	CreateCategory(ctx context.Context, req *models.CreateCategoryRequest) (int64, error)
+	GetTree(ctx context.Context) ([]*models.Category, error)
+	UpdateCategory(ctx context.Context, id int64, req *models.UpdateCategoryRequest) error
+	DeleteCategory(ctx context.Context, id int64) error
+	AddProducts(ctx context.Context, categoryID int64, productIDs []int64) error
+	RemoveProduct(ctx context.Context, categoryID int64, productID int64) error
+	GetProducts(ctx context.Context, categoryID int64, params *models.CategoryProductsParams) (*models.CategoryProductsResponse, error)
+}
+
+type categoryService struct {
+	repo        repositories.CategoryRepository
+	productRepo repositories.ProductRepository
+}
+
+func NewCategoryService(repo repositories.CategoryRepository, productRepo repositories.ProductRepository) CategoryService {
+	return &categoryService{
+		repo:        repo,
+		productRepo: productRepo,
+	}
+}
+
+func (s *categoryService) CreateCategory(ctx context.Context, req *models.CreateCategoryRequest) (int64, error) {
+	return s.repo.Create(ctx, req)
+}
+
+// GetTree собирает плоский список категорий в дерево для меню.
+// Порядок детей сохраняется из List (position, name).
+func (s *categoryService) GetTree(ctx context.Context) ([]*models.Category, error) {
+	categories, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]*models.Category, len(categories))
+	for _, c := range categories {
+		c.Children = make([]*models.Category, 0)
+		byID[c.ID] = c
+	}
+
+	roots := make([]*models.Category, 0)
+	for _, c := range categories {
+		if c.ParentID == nil {
+			roots = append(roots, c)
+			continue
+		}
+		if parent, ok := byID[*c.ParentID]; ok {
+			parent.Children = append(parent.Children, c)
+		}
+	}
+
+	return roots, nil
+}
+
+func (s *categoryService) UpdateCategory(ctx context.Context, id int64, req *models.UpdateCategoryRequest) error {
+	return s.repo.Update(ctx, id, req)
+}
+
+// Категории товара входят в закэшированный товар, поэтому изменения
+// состава категорий сбрасывают кэш каталога.
+func (s *categoryService) DeleteCategory(ctx context.Context, id int64) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.productRepo.InvalidateCache(ctx)
+	return nil
+}
+
+func (s *categoryService) AddProducts(ctx context.Context, categoryID int64, productIDs []int64) error {
+	if err := s.repo.AddProducts(ctx, categoryID, productIDs); err != nil {
+		return err
+	}
+
+	s.productRepo.InvalidateCache(ctx, productIDs...)
+	return nil
+}
+
+func (s *categoryService) RemoveProduct(ctx context.Context, categoryID int64, productID int64) error {
+	if err := s.repo.RemoveProduct(ctx, categoryID, productID); err != nil {
+		return err
+	}
+
+	s.productRepo.InvalidateCache(ctx, productID)
+	return nil
+}
+
+func (s *categoryService) GetProducts(ctx context.Context, categoryID int64, params *models.CategoryProductsParams) (*models.CategoryProductsResponse, error) {
+	if _, err := s.repo.GetByID(ctx, categoryID); err != nil {
+		return nil, err
+	}
+
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultCategoryProductsPageSize
+	}
+
+	products, err := s.repo.ListProducts(ctx, categoryID, params.After, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.CategoryProductsResponse{Items: products}
+	if len(products) > limit {
+		response.Items = products[:limit]
+		response.NextAfter = products[limit-1].ID
+	}
+
+	return response, nil
+}
@@ -0,0 +1,645 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/subtle"
+	"ecommerce-api/internal/config"
+	"ecommerce-api/internal/models"
+	"ecommerce-api/internal/repositories"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	exchangeSessionTTL = time.Hour
+	exchangeFileLimit  = 100 << 20
+	// exchangeUnpackLimit ограничивает общий объём, распакованный из архивов
+	// за одну загрузку каталога.
+	exchangeUnpackLimit = 1 << 30
+	exchangeBatchSize   = 500
+	exchangeOrdersLimit = 1000
+)
+
+var (
+	ErrExchangeDisabled     = errors.New("1c exchange is not configured")
+	ErrExchangeUnauthorized = errors.New("invalid 1c session")
+	ErrInvalidExchangeFile  = errors.New("invalid exchange file")
+)
+
+// ExchangeService реализует обмен с 1С по протоколу CommerceML 2:
+// каталог (import.xml, offers.xml) загружается в products, а новые заказы
+// отдаются в 1С и помечаются выгруженными после подтверждения.
+type ExchangeService interface {
+	CheckAuth(username, password string) (string, error)
+	ValidateSession(sessionID string) error
+	FileLimit() int64
+	SaveFile(sessionID string, filename string, r io.Reader) error
+	ImportCatalog(ctx context.Context, sessionID string, filename string) error
+	WriteOrders(ctx context.Context, sessionID string, w io.Writer) error
+	ConfirmOrders(ctx context.Context, sessionID string) error
+}
+
+type exchangeSession struct {
+	dir             string
+	expiresAt       time.Time
+	pendingOrderIDs []int64
+}
+
+type exchangeService struct {
+	pool        *pgxpool.Pool
+	productRepo repositories.ProductRepository
+	orderRepo   repositories.OrderRepository
+	cfg         config.ExchangeConfig
+
+	mu       sync.Mutex
+	sessions map[string]*exchangeSession
+}
+
+func NewExchangeService(
+	pool *pgxpool.Pool,
+	productRepo repositories.ProductRepository,
+	orderRepo repositories.OrderRepository,
+	cfg config.ExchangeConfig,
+) ExchangeService {
+	return &exchangeService{
+		pool:        pool,
+		productRepo: productRepo,
+		orderRepo:   orderRepo,
+		cfg:         cfg,
+		sessions:    make(map[string]*exchangeSession),
+	}
+}
+
+func (s *exchangeService) CheckAuth(username, password string) (string, error) {
+	if s.cfg.Username == "" || s.cfg.Password == "" {
+		return "", ErrExchangeDisabled
+	}
+
+	userOK := subtle.ConstantTimeCompare([]byte(username), []byte(s.cfg.Username)) == 1
+	passOK := subtle.ConstantTimeCompare([]byte(password), []byte(s.cfg.Password)) == 1
+	if !userOK || !passOK {
+		return "", ErrExchangeUnauthorized
+	}
+
+	sessionID, err := randomName()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(s.cfg.Dir, sessionID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dropExpiredLocked()
+	s.sessions[sessionID] = &exchangeSession{
+		dir:       dir,
+		expiresAt: time.Now().Add(exchangeSessionTTL),
+	}
+
+	return sessionID, nil
+}
+
+func (s *exchangeService) dropExpiredLocked() {
+	now := time.Now()
+	for id, session := range s.sessions {
+		if now.After(session.expiresAt) {
+			os.RemoveAll(session.dir)
+			delete(s.sessions, id)
+		}
+	}
+}
+
+func (s *exchangeService) session(sessionID string) (*exchangeSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[sessionID]
+	if !ok || time.Now().After(session.expiresAt) {
+		return nil, ErrExchangeUnauthorized
+	}
+
+	session.expiresAt = time.Now().Add(exchangeSessionTTL)
+	return session, nil
+}
+
+func (s *exchangeService) ValidateSession(sessionID string) error {
+	_, err := s.session(sessionID)
+	return err
+}
+
+func (s *exchangeService) FileLimit() int64 {
+	return exchangeFileLimit
+}
+
+// sessionPath не даёт имени файла от 1С выйти за каталог сессии.
+// 1С присылает и вложенные пути вида import_files/ab/abc.jpg.
+func sessionPath(dir string, filename string) (string, error) {
+	clean := filepath.Clean("/" + filepath.FromSlash(filename))
+	if clean == string(filepath.Separator) {
+		return "", fmt.Errorf("%w: empty filename", ErrInvalidExchangeFile)
+	}
+	return filepath.Join(dir, clean), nil
+}
+
+// SaveFile дописывает очередную часть файла: большие файлы 1С шлёт кусками
+// не больше file_limit.
+func (s *exchangeService) SaveFile(sessionID string, filename string, r io.Reader) error {
+	session, err := s.session(sessionID)
+	if err != nil {
+		return err
+	}
+
+	path, err := sessionPath(session.dir, filename)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	// Читаем на байт больше лимита, чтобы отличить слишком большую часть
+	// от части ровно в file_limit. Лишнее отрезается, чтобы не испортить файл.
+	n, err := io.Copy(f, io.LimitReader(r, exchangeFileLimit+1))
+	if err == nil && n > exchangeFileLimit {
+		err = fmt.Errorf("%w: chunk exceeds file_limit of %d bytes", ErrInvalidExchangeFile, exchangeFileLimit)
+		if truncErr := f.Truncate(info.Size()); truncErr != nil {
+			log.Printf("1c exchange: failed to truncate %s: %v", path, truncErr)
+		}
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func (s *exchangeService) ImportCatalog(ctx context.Context, sessionID string, filename string) error {
+	session, err := s.session(sessionID)
+	if err != nil {
+		return err
+	}
+
+	if err := unpackArchives(session.dir); err != nil {
+		return err
+	}
+
+	path, err := sessionPath(session.dir, filename)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidExchangeFile, err)
+	}
+	defer f.Close()
+
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.importXML(ctx, tx, f); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	s.productRepo.InvalidateCache(ctx)
+	return nil
+}
+
+type cmlProduct struct {
+	ID          string `xml:"Ид"`
+	SKU         string `xml:"Артикул"`
+	Name        string `xml:"Наименование"`
+	Description string `xml:"Описание"`
+}
+
+type cmlOffer struct {
+	ID       string `xml:"Ид"`
+	Quantity string `xml:"Количество"`
+	Prices   []struct {
+		PerUnit string `xml:"ЦенаЗаЕдиницу"`
+	} `xml:"Цены>Цена"`
+	Stock []struct {
+		Quantity string `xml:"Количество"`
+	} `xml:"Остатки>Остаток"`
+}
+
+// importXML читает файл потоково: и import.xml, и offers.xml разбираются
+// одним проходом по элементам Товар и Предложение.
+func (s *exchangeService) importXML(ctx context.Context, tx pgx.Tx, r io.Reader) error {
+	dec := xml.NewDecoder(r)
+
+	products := make([]models.ExternalProduct, 0, exchangeBatchSize)
+	offers := make([]models.ExternalOffer, 0, exchangeBatchSize)
+	// Предложения характеристик одного товара могут прийти в разных местах
+	// файла, поэтому они копятся до конца и пишутся одним обновлением на товар.
+	characteristics := make(map[string]*models.ExternalOffer)
+	var characteristicIDs []string
+
+	flush := func() error {
+		if len(products) > 0 {
+			if err := s.productRepo.UpsertExternal(ctx, tx, products); err != nil {
+				return err
+			}
+			products = products[:0]
+		}
+		if len(offers) > 0 {
+			if _, err := s.productRepo.UpdateExternalOffers(ctx, tx, offers); err != nil {
+				return err
+			}
+			offers = offers[:0]
+		}
+		return nil
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidExchangeFile, err)
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "Товар":
+			var p cmlProduct
+			if err := dec.DecodeElement(&p, &start); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidExchangeFile, err)
+			}
+			if p.ID == "" || p.Name == "" {
+				continue
+			}
+			products = append(products, models.ExternalProduct{
+				ExternalID:  p.ID,
+				SKU:         strings.TrimSpace(p.SKU),
+				Name:        strings.TrimSpace(p.Name),
+				Description: strings.TrimSpace(p.Description),
+			})
+
+		case "Предложение":
+			var o cmlOffer
+			if err := dec.DecodeElement(&o, &start); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidExchangeFile, err)
+			}
+			offer, ok := o.toExternal()
+			if !ok {
+				continue
+			}
+			if strings.Contains(o.ID, "#") {
+				if merged, seen := characteristics[offer.ExternalID]; seen {
+					mergeOffer(merged, offer)
+				} else {
+					characteristics[offer.ExternalID] = &offer
+					characteristicIDs = append(characteristicIDs, offer.ExternalID)
+				}
+			} else {
+				offers = append(offers, offer)
+			}
+
+		default:
+			continue
+		}
+
+		if len(products)+len(offers) >= exchangeBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, id := range characteristicIDs {
+		offers = append(offers, *characteristics[id])
+		if len(offers) >= exchangeBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return flush()
+}
+
+// mergeOffer сворачивает предложение характеристики в предложение товара:
+// остатки складываются, а ценой товара становится наименьшая цена.
+func mergeOffer(dst *models.ExternalOffer, o models.ExternalOffer) {
+	if o.Price != nil && (dst.Price == nil || *o.Price < *dst.Price) {
+		dst.Price = o.Price
+	}
+	if o.Quantity != nil {
+		total := *o.Quantity
+		if dst.Quantity != nil {
+			total += *dst.Quantity
+		}
+		dst.Quantity = &total
+	}
+}
+
+// toExternal переводит предложение в цену и остаток товара. Ид вида
+// "товар#характеристика" относится к характеристике: такие предложения
+// сворачиваются в сам товар через mergeOffer.
+func (o cmlOffer) toExternal() (models.ExternalOffer, bool) {
+	id, _, _ := strings.Cut(o.ID, "#")
+	if id == "" {
+		return models.ExternalOffer{}, false
+	}
+
+	offer := models.ExternalOffer{ExternalID: id}
+
+	if len(o.Prices) > 0 {
+		if price, err := parseCMLNumber(o.Prices[0].PerUnit); err == nil && price >= 0 {
+			offer.Price = &price
+		}
+	}
+
+	quantity, hasQuantity := 0.0, false
+	if q, err := parseCMLNumber(o.Quantity); err == nil {
+		quantity, hasQuantity = q, true
+	} else {
+		for _, st := range o.Stock {
+			if q, err := parseCMLNumber(st.Quantity); err == nil {
+				quantity += q
+				hasQuantity = true
+			}
+		}
+	}
+	if hasQuantity {
+		inventory := max(0, int(quantity))
+		offer.Quantity = &inventory
+	}
+
+	return offer, true
+}
+
+func parseCMLNumber(s string) (float64, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), " ", "")
+	return strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+}
+
+// unpackArchives распаковывает присланные zip-архивы в каталог сессии.
+func unpackArchives(dir string) error {
+	archives, err := filepath.Glob(filepath.Join(dir, "*.zip"))
+	if err != nil {
+		return err
+	}
+
+	budget := int64(exchangeUnpackLimit)
+	for _, archive := range archives {
+		if err := unzip(archive, dir, &budget); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidExchangeFile, err)
+		}
+		if err := os.Remove(archive); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unzip распаковывает архив, уменьшая budget на объём распакованного.
+// Заголовкам архива верить нельзя, поэтому объём считается при копировании.
+func unzip(archive string, dir string, budget *int64) error {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, zf := range zr.File {
+		if zf.FileInfo().IsDir() {
+			continue
+		}
+
+		path, err := sessionPath(dir, zf.Name)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+
+		src, err := zf.Open()
+		if err != nil {
+			return err
+		}
+
+		dst, err := os.Create(path)
+		if err != nil {
+			src.Close()
+			return err
+		}
+
+		n, err := io.CopyN(dst, src, *budget+1)
+		src.Close()
+		if err == io.EOF {
+			err = nil
+		}
+		if err == nil && n > *budget {
+			err = fmt.Errorf("archive unpacks to more than %d bytes", exchangeUnpackLimit)
+		}
+		if closeErr := dst.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(path)
+			return err
+		}
+		*budget -= n
+	}
+
+	return nil
+}
+
+type cmlDocument struct {
+	XMLName      xml.Name `xml:"Документ"`
+	ID           int64    `xml:"Ид"`
+	Number       int64    `xml:"Номер"`
+	Date         string   `xml:"Дата"`
+	Time         string   `xml:"Время"`
+	Operation    string   `xml:"ХозОперация"`
+	Role         string   `xml:"Роль"`
+	Currency     string   `xml:"Валюта"`
+	Rate         int      `xml:"Курс"`
+	Sum          string   `xml:"Сумма"`
+	Counterparty struct {
+		ID       int64  `xml:"Ид"`
+		Name     string `xml:"Наименование"`
+		FullName string `xml:"ПолноеНаименование"`
+		Role     string `xml:"Роль"`
+	} `xml:"Контрагенты>Контрагент"`
+	Items      []cmlDocumentItem `xml:"Товары>Товар"`
+	Properties []cmlProperty     `xml:"ЗначенияРеквизитов>ЗначениеРеквизита"`
+}
+
+type cmlDocumentItem struct {
+	ID       string `xml:"Ид"`
+	SKU      string `xml:"Артикул,omitempty"`
+	Name     string `xml:"Наименование"`
+	Unit     cmlUnit
+	PerUnit  string `xml:"ЦенаЗаЕдиницу"`
+	Quantity int    `xml:"Количество"`
+	Sum      string `xml:"Сумма"`
+}
+
+type cmlUnit struct {
+	XMLName  xml.Name `xml:"БазоваяЕдиница"`
+	Code     string   `xml:"Код,attr"`
+	FullName string   `xml:"НаименованиеПолное,attr"`
+	Intl     string   `xml:"МеждународноеСокращение,attr"`
+	Name     string   `xml:",chardata"`
+}
+
+type cmlProperty struct {
+	Name  string `xml:"Наименование"`
+	Value string `xml:"Значение"`
+}
+
+// WriteOrders отдаёт невыгруженные заказы в формате CommerceML. Выгруженными
+// они считаются только после mode=success от 1С.
+func (s *exchangeService) WriteOrders(ctx context.Context, sessionID string, w io.Writer) error {
+	session, err := s.session(sessionID)
+	if err != nil {
+		return err
+	}
+
+	orders, err := s.orderRepo.ListUnexported(ctx, exchangeOrdersLimit)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	now := time.Now()
+	root := xml.StartElement{
+		Name: xml.Name{Local: "КоммерческаяИнформация"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "ВерсияСхемы"}, Value: "2.08"},
+			{Name: xml.Name{Local: "ДатаФормирования"}, Value: now.Format("2006-01-02T15:04:05")},
+		},
+	}
+	if err := enc.EncodeToken(root); err != nil {
+		return err
+	}
+
+	ids := make([]int64, 0, len(orders))
+	for _, o := range orders {
+		if err := enc.Encode(newCMLDocument(o)); err != nil {
+			return err
+		}
+		ids = append(ids, o.ID)
+	}
+
+	if err := enc.EncodeToken(root.End()); err != nil {
+		return err
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	session.pendingOrderIDs = ids
+	s.mu.Unlock()
+
+	return nil
+}
+
+func newCMLDocument(o *models.ExportOrder) cmlDocument {
+	doc := cmlDocument{
+		ID:        o.ID,
+		Number:    o.ID,
+		Date:      o.CreatedAt.Format("2006-01-02"),
+		Time:      o.CreatedAt.Format("15:04:05"),
+		Operation: "Заказ товара",
+		Role:      "Продавец",
+		Currency:  "RUB",
+		Rate:      1,
+		Sum:       strconv.FormatFloat(o.TotalAmount, 'f', 2, 64),
+		Properties: []cmlProperty{
+			{Name: "Статус заказа", Value: o.Status},
+		},
+	}
+
+	doc.Counterparty.ID = o.UserID
+	doc.Counterparty.Name = o.Email
+	doc.Counterparty.FullName = o.Email
+	doc.Counterparty.Role = "Покупатель"
+
+	for _, item := range o.Items {
+		id := item.ExternalID
+		if id == "" {
+			id = strconv.FormatInt(item.ProductID, 10)
+		}
+
+		doc.Items = append(doc.Items, cmlDocumentItem{
+			ID:       id,
+			SKU:      item.SKU,
+			Name:     item.Name,
+			Unit:     cmlUnit{Code: "796", FullName: "Штука", Intl: "PCE", Name: "шт"},
+			PerUnit:  strconv.FormatFloat(item.PriceAtPurchase, 'f', 2, 64),
+			Quantity: item.Quantity,
+			Sum:      strconv.FormatFloat(float64(item.Quantity)*item.PriceAtPurchase, 'f', 2, 64),
+		})
+	}
+
+	return doc
+}
+
+func (s *exchangeService) ConfirmOrders(ctx context.Context, sessionID string) error {
+	session, err := s.session(sessionID)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	ids := session.pendingOrderIDs
+	session.pendingOrderIDs = nil
+	s.mu.Unlock()
+
+	return s.orderRepo.MarkExported(ctx, ids)
+}
@@ -0,0 +1,346 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"ecommerce-api/internal/config"
+	"ecommerce-api/internal/models"
+	"ecommerce-api/internal/repositories"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	feedBatchSize        = 500
+	ymlMaxPictures       = 10
+	yandexFeedFileName   = "yandex.yml"
+	ymlCatalogDateFormat = "2006-01-02T15:04-07:00"
+)
+
+type FeedService interface {
+	// YandexFeedPath возвращает путь к актуальному файлу фида,
+	// перегенерируя его, если каталог изменился.
+	YandexFeedPath(ctx context.Context) (string, error)
+}
+
+type feedService struct {
+	productRepo  repositories.ProductRepository
+	categoryRepo repositories.CategoryRepository
+	variantRepo  repositories.VariantRepository
+	imageSvc     ImageService
+	cfg          config.FeedConfig
+
+	mu      sync.Mutex
+	version string
+}
+
+func NewFeedService(
+	productRepo repositories.ProductRepository,
+	categoryRepo repositories.CategoryRepository,
+	variantRepo repositories.VariantRepository,
+	imageSvc ImageService,
+	cfg config.FeedConfig,
+) FeedService {
+	return &feedService{
+		productRepo:  productRepo,
+		categoryRepo: categoryRepo,
+		variantRepo:  variantRepo,
+		imageSvc:     imageSvc,
+		cfg:          cfg,
+	}
+}
+
+func (s *feedService) YandexFeedPath(ctx context.Context) (string, error) {
+	version, err := s.productRepo.CatalogVersion(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(s.cfg.CacheDir, yandexFeedFileName)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.version == version {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+
+	if err := s.generateYandexFeed(ctx, path); err != nil {
+		return "", err
+	}
+
+	s.version = version
+	return path, nil
+}
+
+type ymlCurrency struct {
+	XMLName xml.Name `xml:"currency"`
+	ID      string   `xml:"id,attr"`
+	Rate    string   `xml:"rate,attr"`
+}
+
+type ymlCategory struct {
+	XMLName  xml.Name `xml:"category"`
+	ID       int64    `xml:"id,attr"`
+	ParentID int64    `xml:"parentId,attr,omitempty"`
+	Name     string   `xml:",chardata"`
+}
+
+type ymlOffer struct {
+	XMLName xml.Name `xml:"offer"`
+	ID      string   `xml:"id,attr"`
+	// GroupID объединяет предложения вариантов одного товара.
+	GroupID     int64      `xml:"group_id,attr,omitempty"`
+	Available   bool       `xml:"available,attr"`
+	Name        string     `xml:"name"`
+	URL         string     `xml:"url"`
+	Price       string     `xml:"price"`
+	CurrencyID  string     `xml:"currencyId"`
+	CategoryID  int64      `xml:"categoryId,omitempty"`
+	Pictures    []string   `xml:"picture"`
+	VendorCode  string     `xml:"vendorCode,omitempty"`
+	Description string     `xml:"description,omitempty"`
+	Params      []ymlParam `xml:"param"`
+	Count       int        `xml:"count"`
+}
+
+type ymlParam struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
+
+// generateYandexFeed пишет фид во временный файл и атомарно подменяет им
+// старый, так что параллельные запросы никогда не видят файл наполовину.
+func (s *feedService) generateYandexFeed(ctx context.Context, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), yandexFeedFileName+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	if err := s.writeYandexFeed(ctx, w); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to generate yandex feed: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *feedService) writeYandexFeed(ctx context.Context, w *bufio.Writer) error {
+	if _, err := w.WriteString(xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	catalog := xml.StartElement{
+		Name: xml.Name{Local: "yml_catalog"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "date"}, Value: time.Now().Format(ymlCatalogDateFormat)}},
+	}
+	shop := xml.StartElement{Name: xml.Name{Local: "shop"}}
+
+	if err := enc.EncodeToken(catalog); err != nil {
+		return err
+	}
+	if err := enc.EncodeToken(shop); err != nil {
+		return err
+	}
+
+	for _, el := range []struct{ name, value string }{
+		{"name", s.cfg.ShopName},
+		{"company", s.cfg.Company},
+		{"url", s.cfg.ShopURL},
+	} {
+		if err := enc.EncodeElement(el.value, xml.StartElement{Name: xml.Name{Local: el.name}}); err != nil {
+			return err
+		}
+	}
+
+	currencies := struct {
+		XMLName  xml.Name `xml:"currencies"`
+		Currency ymlCurrency
+	}{Currency: ymlCurrency{ID: "RUB", Rate: "1"}}
+	if err := enc.Encode(currencies); err != nil {
+		return err
+	}
+
+	if err := s.writeCategories(ctx, enc); err != nil {
+		return err
+	}
+
+	offers := xml.StartElement{Name: xml.Name{Local: "offers"}}
+	if err := enc.EncodeToken(offers); err != nil {
+		return err
+	}
+
+	batch := make([]*models.Product, 0, feedBatchSize)
+	err := s.productRepo.ForEach(ctx, func(p *models.Product) error {
+		batch = append(batch, p)
+		if len(batch) < feedBatchSize {
+			return nil
+		}
+		err := s.writeOffers(ctx, enc, batch)
+		batch = batch[:0]
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.writeOffers(ctx, enc, batch); err != nil {
+		return err
+	}
+
+	for _, end := range []xml.StartElement{offers, shop, catalog} {
+		if err := enc.EncodeToken(end.End()); err != nil {
+			return err
+		}
+	}
+
+	return enc.Flush()
+}
+
+func (s *feedService) writeCategories(ctx context.Context, enc *xml.Encoder) error {
+	categories, err := s.categoryRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: "categories"}}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	for _, c := range categories {
+		category := ymlCategory{ID: c.ID, Name: c.Name}
+		if c.ParentID != nil {
+			category.ParentID = *c.ParentID
+		}
+		if err := enc.Encode(category); err != nil {
+			return err
+		}
+	}
+
+	return enc.EncodeToken(start.End())
+}
+
+// writeOffers подгружает картинки сразу для всей пачки товаров, чтобы не
+// делать запрос на каждый offer.
+func (s *feedService) writeOffers(ctx context.Context, enc *xml.Encoder, products []*models.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+
+	images, err := s.imageSvc.ListByProducts(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	variantList, err := s.variantRepo.ListByProducts(ctx, ids)
+	if err != nil {
+		return err
+	}
+	variants := make(map[int64][]*models.ProductVariant)
+	for _, v := range variantList {
+		variants[v.ProductID] = append(variants[v.ProductID], v)
+	}
+
+	for _, p := range products {
+		offer := ymlOffer{
+			ID:          strconv.FormatInt(p.ID, 10),
+			Available:   p.Inventory > 0,
+			Name:        p.Name,
+			URL:         strings.ReplaceAll(s.cfg.ProductURLTemplate, "{id}", strconv.FormatInt(p.ID, 10)),
+			Price:       strconv.FormatFloat(p.Price, 'f', 2, 64),
+			CurrencyID:  "RUB",
+			VendorCode:  p.SKU,
+			Description: p.Description,
+			Count:       p.Inventory,
+		}
+
+		if len(p.CategoryIDs) > 0 {
+			offer.CategoryID = p.CategoryIDs[0]
+		}
+
+		for _, img := range images[p.ID] {
+			if len(offer.Pictures) == ymlMaxPictures {
+				break
+			}
+			offer.Pictures = append(offer.Pictures, s.absoluteURL(img.URL))
+		}
+
+		if len(variants[p.ID]) == 0 {
+			if err := enc.Encode(offer); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// У товара с вариантами цена и остаток ведутся по вариантам, поэтому
+		// каждый вариант — отдельное предложение в группе товара.
+		for _, v := range variants[p.ID] {
+			variantOffer := offer
+			variantOffer.ID = strconv.FormatInt(p.ID, 10) + "v" + strconv.FormatInt(v.ID, 10)
+			variantOffer.GroupID = p.ID
+			variantOffer.Available = v.Inventory > 0
+			variantOffer.Price = strconv.FormatFloat(v.PriceFor(p), 'f', 2, 64)
+			variantOffer.VendorCode = v.SKU
+			variantOffer.Count = v.Inventory
+			variantOffer.Params = variantParams(v)
+
+			if err := enc.Encode(variantOffer); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// variantParams переводит опции варианта в param, по имени опции.
+func variantParams(v *models.ProductVariant) []ymlParam {
+	names := make([]string, 0, len(v.Options))
+	for name := range v.Options {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	params := make([]ymlParam, len(names))
+	for i, name := range names {
+		params[i] = ymlParam{Name: name, Value: v.Options[name]}
+	}
+	return params
+}
+
+func (s *feedService) absoluteURL(u string) string {
+	if strings.HasPrefix(u, "/") {
+		return s.cfg.ShopURL + u
+	}
+	return u
+}
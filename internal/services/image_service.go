@@ -0,0 +1,205 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"ecommerce-api/internal/models"
+	"ecommerce-api/internal/repositories"
+	"ecommerce-api/internal/storage"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+)
+
+const (
+	maxImageUploadSize = 10 << 20
+	maxImagePixels     = 40_000_000
+	thumbnailMaxSide   = 320
+)
+
+var ErrInvalidImage = errors.New("invalid image")
+
+type ImageService interface {
+	Upload(ctx context.Context, productID int64, r io.Reader, altText string, position *int) (*models.ProductImage, error)
+	Update(ctx context.Context, productID int64, imageID int64, req *models.UpdateProductImageRequest) (*models.ProductImage, error)
+	Delete(ctx context.Context, productID int64, imageID int64) error
+	ListByProducts(ctx context.Context, productIDs []int64) (map[int64][]*models.ProductImage, error)
+	MainImageURLs(ctx context.Context, productIDs []int64) (map[int64]string, error)
+}
+
+type imageService struct {
+	productRepo repositories.ProductRepository
+	imageRepo   repositories.ImageRepository
+	blobs       storage.BlobStorage
+}
+
+func NewImageService(productRepo repositories.ProductRepository, imageRepo repositories.ImageRepository, blobs storage.BlobStorage) ImageService {
+	return &imageService{
+		productRepo: productRepo,
+		imageRepo:   imageRepo,
+		blobs:       blobs,
+	}
+}
+
+func (s *imageService) Upload(ctx context.Context, productID int64, r io.Reader, altText string, position *int) (*models.ProductImage, error) {
+	if _, err := s.productRepo.GetByID(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, maxImageUploadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImageUploadSize {
+		return nil, fmt.Errorf("%w: file is larger than %d bytes", ErrInvalidImage, maxImageUploadSize)
+	}
+
+	// Размеры проверяются до полного декодирования, чтобы маленький файл
+	// не развернулся в гигабайты пикселей.
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return nil, fmt.Errorf("%w: image is too large", ErrInvalidImage)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	var thumb bytes.Buffer
+	thumbExt, thumbType := ".png", "image/png"
+	if format == "jpeg" {
+		thumbExt, thumbType = ".jpg", "image/jpeg"
+		err = jpeg.Encode(&thumb, resizeToFit(img, thumbnailMaxSide), &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(&thumb, resizeToFit(img, thumbnailMaxSide))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+
+	name, err := randomName()
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := "image/" + format
+	ext := map[string]string{"jpeg": ".jpg", "png": ".png", "gif": ".gif"}[format]
+
+	fileKey := fmt.Sprintf("products/%d/%s%s", productID, name, ext)
+	thumbKey := fmt.Sprintf("products/%d/%s_thumb%s", productID, name, thumbExt)
+
+	if err := s.blobs.Put(ctx, fileKey, bytes.NewReader(data), contentType); err != nil {
+		return nil, fmt.Errorf("failed to store image: %w", err)
+	}
+	if err := s.blobs.Put(ctx, thumbKey, &thumb, thumbType); err != nil {
+		s.removeFiles(ctx, fileKey)
+		return nil, fmt.Errorf("failed to store thumbnail: %w", err)
+	}
+
+	productImage := &models.ProductImage{
+		ProductID:    productID,
+		FileKey:      fileKey,
+		ThumbnailKey: thumbKey,
+		ContentType:  contentType,
+		Width:        cfg.Width,
+		Height:       cfg.Height,
+		AltText:      altText,
+	}
+
+	if err := s.imageRepo.Create(ctx, productImage, position); err != nil {
+		s.removeFiles(ctx, fileKey, thumbKey)
+		return nil, err
+	}
+	s.productRepo.InvalidateCache(ctx, productID)
+
+	s.fillURLs(productImage)
+	return productImage, nil
+}
+
+func (s *imageService) Update(ctx context.Context, productID int64, imageID int64, req *models.UpdateProductImageRequest) (*models.ProductImage, error) {
+	img, err := s.imageRepo.Update(ctx, productID, imageID, req)
+	if err != nil {
+		return nil, err
+	}
+	s.productRepo.InvalidateCache(ctx, productID)
+
+	s.fillURLs(img)
+	return img, nil
+}
+
+func (s *imageService) Delete(ctx context.Context, productID int64, imageID int64) error {
+	img, err := s.imageRepo.GetByID(ctx, productID, imageID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.imageRepo.Delete(ctx, productID, imageID); err != nil {
+		return err
+	}
+	s.productRepo.InvalidateCache(ctx, productID)
+
+	s.removeFiles(ctx, img.FileKey, img.ThumbnailKey)
+	return nil
+}
+
+func (s *imageService) ListByProducts(ctx context.Context, productIDs []int64) (map[int64][]*models.ProductImage, error) {
+	images, err := s.imageRepo.ListByProducts(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byProduct := make(map[int64][]*models.ProductImage)
+	for _, img := range images {
+		s.fillURLs(img)
+		byProduct[img.ProductID] = append(byProduct[img.ProductID], img)
+	}
+
+	return byProduct, nil
+}
+
+// MainImageURLs возвращает превью первой по порядку картинки каждого товара.
+func (s *imageService) MainImageURLs(ctx context.Context, productIDs []int64) (map[int64]string, error) {
+	byProduct, err := s.ListByProducts(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make(map[int64]string, len(byProduct))
+	for productID, images := range byProduct {
+		urls[productID] = images[0].ThumbnailURL
+	}
+
+	return urls, nil
+}
+
+func (s *imageService) fillURLs(img *models.ProductImage) {
+	img.URL = s.blobs.URL(img.FileKey)
+	img.ThumbnailURL = s.blobs.URL(img.ThumbnailKey)
+}
+
+func (s *imageService) removeFiles(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if err := s.blobs.Delete(ctx, key); err != nil {
+			log.Printf("warning: failed to delete file %s: %v", key, err)
+		}
+	}
+}
+
+func randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -4,46 +4,92 @@ import (
 	"context"
 	"ecommerce-api/internal/models"
 	"ecommerce-api/internal/repositories"
+	"errors"
 	"fmt"
 	"log"
+	"slices"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrCartChanged означает, что клиент оформляет заказ по устаревшему
+// представлению корзины: ему нужно заново получить корзину и подтвердить цены.
+var ErrCartChanged = errors.New("cart has changed since it was last viewed, review it and confirm with the current checksum")
+
+var ErrInvalidOrderTransition = errors.New("invalid order status transition")
+
+// orderTransitions — разрешённые переходы между статусами заказа.
+// canceled и refunded — конечные.
+var orderTransitions = map[models.OrderStatus][]models.OrderStatus{
+	models.OrderPending:         {models.OrderAwaitingPayment, models.OrderPaid, models.OrderCanceled},
+	models.OrderAwaitingPayment: {models.OrderPaid, models.OrderCanceled},
+	models.OrderPaid:            {models.OrderProcessing, models.OrderRefunded},
+	models.OrderProcessing:      {models.OrderShipped, models.OrderRefunded},
+	models.OrderShipped:         {models.OrderDelivered, models.OrderRefunded},
+	models.OrderDelivered:       {models.OrderRefunded},
+}
+
+// allowedFromStatuses — статусы, из которых можно перейти в to.
+func allowedFromStatuses(to models.OrderStatus) []models.OrderStatus {
+	var from []models.OrderStatus
+	for status, next := range orderTransitions {
+		if slices.Contains(next, to) {
+			from = append(from, status)
+		}
+	}
+	return from
+}
+
 type OrderService interface {
-	CreateOrder(ctx context.Context, userID int64) (*models.CreateOrderResponse, error)
+	CreateOrder(ctx context.Context, userID int64, req *models.CreateOrderRequest) (*models.CreateOrderResponse, error)
 	ListOrders(ctx context.Context, userID int64) ([]*models.OrderResponse, error)
 	GetOrderByID(ctx context.Context, orderID int64, userID int64) (*models.OrderResponse, error)
-	UpdateOrderStatus(ctx context.Context, orderID int64, newStatus string) error
+	// UpdateOrderStatus переводит заказ в newStatus по таблице orderTransitions.
+	// Повторный переход в текущий статус ничего не делает: вебхуки приходят
+	// повторно. Недопустимый переход — ErrInvalidOrderTransition.
+	UpdateOrderStatus(ctx context.Context, orderID int64, newStatus models.OrderStatus, change models.OrderStatusChange) error
+	GetOrderHistory(ctx context.Context, orderID int64, userID int64) ([]models.OrderStatusHistoryEntry, error)
 }
 
 type orderService struct {
 	pool        *pgxpool.Pool
 	productRepo repositories.ProductRepository
+	variantRepo repositories.VariantRepository
 	cartRepo    repositories.CartRepository
 	orderRepo   repositories.OrderRepository
+	ruleRepo    repositories.PurchaseRuleRepository
 	paymentSvc  PaymentService
+	imageSvc    ImageService
 }
 
 func NewOrderService(
 	pool *pgxpool.Pool,
 	productRepo repositories.ProductRepository,
+	variantRepo repositories.VariantRepository,
 	cartRepo repositories.CartRepository,
 	orderRepo repositories.OrderRepository,
+	ruleRepo repositories.PurchaseRuleRepository,
 	paymentSvc PaymentService,
+	imageSvc ImageService,
 ) OrderService {
 	return &orderService{
 		pool:        pool,
 		productRepo: productRepo,
+		variantRepo: variantRepo,
 		cartRepo:    cartRepo,
 		orderRepo:   orderRepo,
+		ruleRepo:    ruleRepo,
 		paymentSvc:  paymentSvc,
+		imageSvc:    imageSvc,
 	}
 }
 
-func (s *orderService) CreateOrder(ctx context.Context, userID int64) (*models.CreateOrderResponse, error) {
-	cartMap, err := s.cartRepo.GetCart(ctx, userID)
+// CreateOrder оформляет заказ по текущим ценам. Если цена какой-то позиции
+// изменилась после добавления в корзину, заказ создаётся только при
+// совпадении req.CartChecksum с контрольной суммой корзины.
+func (s *orderService) CreateOrder(ctx context.Context, userID int64, req *models.CreateOrderRequest) (*models.CreateOrderResponse, error) {
+	cartMap, cartVersion, err := s.cartRepo.GetCart(ctx, models.UserCart(userID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get cart: %w", err)
 	}
@@ -52,55 +98,112 @@ func (s *orderService) CreateOrder(ctx context.Context, userID int64) (*models.C
 		return nil, fmt.Errorf("cart is empty")
 	}
 
-	productIDs := make([]int64, 0, len(cartMap))
-	for id := range cartMap {
-		productIDs = append(productIDs, id)
-	}
-
-	products, err := s.productRepo.GetByIDs(ctx, productIDs)
+	productMap, variantMap, err := loadCartItems(ctx, s.productRepo, s.variantRepo, cartMap)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get products: %w", err)
 	}
 
-	productMap := make(map[int64]*models.Product)
-	for _, p := range products {
-		productMap[p.ID] = p
+	productIDs := make([]int64, 0, len(productMap))
+	for id := range productMap {
+		productIDs = append(productIDs, id)
+	}
+
+	// Правила могли поменяться после добавления в корзину, а лимит на
+	// покупателя у гостя проверить было нельзя, поэтому проверяем ещё раз.
+	rules, err := s.ruleRepo.GetByProductIDs(ctx, productIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get purchase rules: %w", err)
 	}
 
 	var total float64
+	var pricesChanged bool
 	items := make([]models.OrderItem, 0, len(cartMap))
-	for productID, quantity := range cartMap {
-		product, ok := productMap[productID]
+	lines := make([]checksumLine, 0, len(cartMap))
+	for cartItem, line := range cartMap {
+		quantity := line.Quantity
+		product, ok := productMap[cartItem.ProductID]
 		if !ok {
-			return nil, fmt.Errorf("product %d not found", productID)
+			return nil, fmt.Errorf("product %d not found", cartItem.ProductID)
 		}
 
-		if quantity > product.Inventory {
-			return nil, fmt.Errorf("not enough inventory for product %d: need %d, available %d", productID, quantity, product.Inventory)
+		price := product.Price
+		inventory := product.Inventory
+		var variantID *int64
+
+		if cartItem.VariantID != 0 {
+			variant, ok := variantMap[cartItem.VariantID]
+			if !ok || variant.ProductID != product.ID {
+				return nil, fmt.Errorf("variant %d of product %d not found", cartItem.VariantID, cartItem.ProductID)
+			}
+			price = variant.PriceFor(product)
+			inventory = variant.Inventory
+			variantID = &variant.ID
 		}
 
-		subtotal := float64(quantity) * product.Price
+		others := otherVariantsQuantity(cartMap, cartItem)
+		if err := checkPurchaseRules(ctx, s.ruleRepo, rules[product.ID], userID, cartItem, quantity, others); err != nil {
+			return nil, err
+		}
+
+		if quantity > inventory {
+			return nil, fmt.Errorf("not enough inventory for product %d: need %d, available %d", cartItem.ProductID, quantity, inventory)
+		}
+
+		subtotal := float64(quantity) * price
 		total += subtotal
 
+		if priceChanged(line.Price, price) {
+			pricesChanged = true
+		}
+		lines = append(lines, checksumLine{
+			ProductID: cartItem.ProductID,
+			VariantID: cartItem.VariantID,
+			Quantity:  quantity,
+			Price:     price,
+		})
+
 		items = append(items, models.OrderItem{
-			ProductID:       productID,
+			ProductID:       cartItem.ProductID,
+			VariantID:       variantID,
 			Quantity:        quantity,
-			PriceAtPurchase: product.Price,
+			PriceAtPurchase: price,
 		})
 	}
 
+	checksum := cartChecksum(lines)
+	if (pricesChanged || req.CartChecksum != "") && req.CartChecksum != checksum {
+		return nil, ErrCartChanged
+	}
+
 	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 
 	for _, item := range items {
+		// У товаров с вариантами остаток ведётся по каждому варианту отдельно.
 		updateQuery := `
 			UPDATE products
-			SET inventory = inventory - $1
+			SET inventory = inventory - $1,
+				updated_at = NOW()
 			WHERE id = $2 AND inventory >= $1`
+		args := []any{item.Quantity, item.ProductID}
 
-		result, err := tx.Exec(ctx, updateQuery, item.Quantity, item.ProductID)
+		// Остатки вариантов входят в карточку товара, поэтому updated_at
+		// товара тоже сдвигается: иначе If-Modified-Since отдаст 304.
+		if item.VariantID != nil {
+			updateQuery = `
+				WITH touched AS (
+					UPDATE products SET updated_at = NOW() WHERE id = $3
+				)
+				UPDATE product_variants
+				SET inventory = inventory - $1,
+					updated_at = NOW()
+				WHERE id = $2 AND product_id = $3 AND inventory >= $1`
+			args = []any{item.Quantity, *item.VariantID, item.ProductID}
+		}
+
+		result, err := tx.Exec(ctx, updateQuery, args...)
 		if err != nil {
 			tx.Rollback(ctx)
 			return nil, fmt.Errorf("failed to update inventory for product %d: %w", item.ProductID, err)
@@ -114,7 +217,7 @@ func (s *orderService) CreateOrder(ctx context.Context, userID int64) (*models.C
 
 	order := &models.Order{
 		UserID:      userID,
-		Status:      "pending",
+		Status:      models.OrderPending,
 		TotalAmount: total,
 	}
 
@@ -127,13 +230,33 @@ func (s *orderService) CreateOrder(ctx context.Context, userID int64) (*models.C
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	if err := s.cartRepo.ClearCart(ctx, userID); err != nil {
+	s.productRepo.InvalidateCache(ctx, productIDs...)
+
+	// Если корзину поменяли, пока оформлялся заказ (например, из другой
+	// вкладки), она остаётся как есть, чтобы не потерять новые позиции.
+	cleared, err := s.cartRepo.ClearCartIfVersion(ctx, models.UserCart(userID), cartVersion)
+	switch {
+	case err != nil:
 		log.Printf("warning: failed to clear cart for user %d: %v", userID, err)
+	case !cleared:
+		log.Printf("cart of user %d changed during checkout of order %d, left intact", userID, order.ID)
 	}
 
 	paymentURL, err := s.paymentSvc.CreatePayment(ctx, order)
 	if err != nil {
 		log.Printf("warning: failed to create payment for order %d: %v", order.ID, err)
+	} else {
+		// Вебхук об оплате мог успеть прийти раньше: тогда заказ уже paid
+		// и переход просто не состоится.
+		err := s.UpdateOrderStatus(ctx, order.ID, models.OrderAwaitingPayment, models.OrderStatusChange{
+			Source: models.OrderSourceSystem,
+		})
+		switch {
+		case err == nil:
+			order.Status = models.OrderAwaitingPayment
+		case !errors.Is(err, ErrInvalidOrderTransition):
+			log.Printf("warning: failed to mark order %d as awaiting payment: %v", order.ID, err)
+		}
 	}
 
 	return &models.CreateOrderResponse{
@@ -150,9 +273,39 @@ func (s *orderService) ListOrders(ctx context.Context, userID int64) ([]*models.
 }
 
 func (s *orderService) GetOrderByID(ctx context.Context, orderID int64, userID int64) (*models.OrderResponse, error) {
-	return s.orderRepo.GetOrderByID(ctx, orderID, userID)
+	order, err := s.orderRepo.GetOrderByID(ctx, orderID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	productIDs := make([]int64, 0, len(order.Items))
+	for _, item := range order.Items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+
+	imageURLs, err := s.imageSvc.MainImageURLs(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range order.Items {
+		order.Items[i].ImageURL = imageURLs[order.Items[i].ProductID]
+	}
+
+	return order, nil
+}
+
+func (s *orderService) UpdateOrderStatus(ctx context.Context, orderID int64, newStatus models.OrderStatus, change models.OrderStatusChange) error {
+	current, err := s.orderRepo.UpdateOrderStatus(ctx, orderID, allowedFromStatuses(newStatus), newStatus, change)
+	if errors.Is(err, repositories.ErrOrderStatusConflict) {
+		if current == newStatus {
+			return nil
+		}
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidOrderTransition, current, newStatus)
+	}
+	return err
 }
 
-func (s *orderService) UpdateOrderStatus(ctx context.Context, orderID int64, newStatus string) error {
-	return s.orderRepo.UpdateOrderStatus(ctx, orderID, newStatus)
+func (s *orderService) GetOrderHistory(ctx context.Context, orderID int64, userID int64) ([]models.OrderStatusHistoryEntry, error) {
+	return s.orderRepo.ListStatusHistory(ctx, orderID, userID)
 }
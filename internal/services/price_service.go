@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"ecommerce-api/internal/models"
+	"ecommerce-api/internal/repositories"
+	"errors"
+	"log"
+	"time"
+)
+
+const (
+	defaultPriceHistoryLimit = 100
+	lowestPriceWindow        = 30 * 24 * time.Hour
+)
+
+var ErrApplyAtInPast = errors.New("apply_at must be in the future")
+
+type PriceService interface {
+	GetHistory(ctx context.Context, productID int64, params *models.PriceHistoryParams) (*models.PriceHistoryResponse, error)
+	SchedulePrice(ctx context.Context, productID int64, req *models.SchedulePriceRequest) (*models.ScheduledPrice, error)
+	ListScheduled(ctx context.Context, productID int64) ([]*models.ScheduledPrice, error)
+	CancelScheduled(ctx context.Context, productID int64, id int64) error
+	RunScheduler(ctx context.Context, interval time.Duration)
+}
+
+type priceService struct {
+	productRepo repositories.ProductRepository
+	priceRepo   repositories.PriceRepository
+}
+
+func NewPriceService(productRepo repositories.ProductRepository, priceRepo repositories.PriceRepository) PriceService {
+	return &priceService{
+		productRepo: productRepo,
+		priceRepo:   priceRepo,
+	}
+}
+
+func (s *priceService) GetHistory(ctx context.Context, productID int64, params *models.PriceHistoryParams) (*models.PriceHistoryResponse, error) {
+	if _, err := s.productRepo.GetByID(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultPriceHistoryLimit
+	}
+
+	items, err := s.priceRepo.ListHistory(ctx, productID, params.From, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	lowest, err := s.priceRepo.LowestSince(ctx, productID, time.Now().Add(-lowestPriceWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PriceHistoryResponse{
+		Items:        items,
+		Lowest30Days: lowest,
+	}, nil
+}
+
+func (s *priceService) SchedulePrice(ctx context.Context, productID int64, req *models.SchedulePriceRequest) (*models.ScheduledPrice, error) {
+	if !req.ApplyAt.After(time.Now()) {
+		return nil, ErrApplyAtInPast
+	}
+
+	if _, err := s.productRepo.GetByID(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	sp := &models.ScheduledPrice{
+		ProductID: productID,
+		Price:     req.Price,
+		ApplyAt:   req.ApplyAt,
+	}
+	if err := s.priceRepo.Schedule(ctx, sp); err != nil {
+		return nil, err
+	}
+
+	return sp, nil
+}
+
+func (s *priceService) ListScheduled(ctx context.Context, productID int64) ([]*models.ScheduledPrice, error) {
+	return s.priceRepo.ListScheduled(ctx, productID)
+}
+
+func (s *priceService) CancelScheduled(ctx context.Context, productID int64, id int64) error {
+	return s.priceRepo.CancelScheduled(ctx, productID, id)
+}
+
+// RunScheduler применяет запланированные цены раз в interval, пока не
+// отменён ctx. Первый проход — сразу при старте, чтобы догнать изменения,
+// пропущенные во время простоя.
+func (s *priceService) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		updated, err := s.priceRepo.ApplyDue(ctx)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			log.Printf("price scheduler error: %v", err)
+		case len(updated) > 0:
+			s.productRepo.InvalidateCache(ctx, updated...)
+			log.Printf("price scheduler: updated %d products", len(updated))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
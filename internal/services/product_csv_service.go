@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"ecommerce-api/internal/models"
+	"ecommerce-api/internal/repositories"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const importBatchSize = 500
+
+var (
+	ErrInvalidCSV = errors.New("invalid csv")
+
+	productCSVHeader = []string{"sku", "name", "description", "price", "inventory"}
+)
+
+type ProductCSVService interface {
+	Import(ctx context.Context, r io.Reader, delimiter rune, dryRun bool) (*models.ImportReport, error)
+	Export(ctx context.Context, w io.Writer, delimiter rune) error
+}
+
+type productCSVService struct {
+	pool        *pgxpool.Pool
+	productRepo repositories.ProductRepository
+}
+
+func NewProductCSVService(pool *pgxpool.Pool, productRepo repositories.ProductRepository) ProductCSVService {
+	return &productCSVService{
+		pool:        pool,
+		productRepo: productRepo,
+	}
+}
+
+// importRow — проверенная строка CSV вместе с её номером для отчёта.
+type importRow struct {
+	num int
+	req *models.ImportProduct
+}
+
+// Import читает CSV построчно и пишет товары батчами в одной транзакции.
+// Невалидные строки и строки, которые отвергла база, попадают в отчёт и не
+// мешают импорту остальных. Пробный прогон делает то же самое и откатывает
+// транзакцию, поэтому его отчёт совпадает с настоящим импортом.
+func (s *productCSVService) Import(ctx context.Context, r io.Reader, delimiter rune, dryRun bool) (*models.ImportReport, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read header: %v", ErrInvalidCSV, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		columns[name] = i
+	}
+	for _, required := range []string{"sku", "name", "price"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%w: missing column %q", ErrInvalidCSV, required)
+		}
+	}
+
+	report := &models.ImportReport{
+		DryRun: dryRun,
+		Errors: make([]models.ImportRowError, 0),
+	}
+
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	seen := make(map[string]int)
+	batch := make([]importRow, 0, importBatchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := s.upsertBatch(ctx, tx, batch, report); err != nil {
+			return err
+		}
+
+		batch = batch[:0]
+		return nil
+	}
+
+	for rowNum := 2; ; rowNum++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		report.TotalRows++
+
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, err
+			}
+			report.AddError(rowNum, "", err.Error())
+			continue
+		}
+
+		req, err := parseProductRecord(record, columns)
+		if err != nil {
+			report.AddError(rowNum, req.SKU, err.Error())
+			continue
+		}
+
+		if prev, ok := seen[req.SKU]; ok {
+			report.AddError(rowNum, req.SKU, fmt.Sprintf("duplicate sku, first seen in row %d", prev))
+			continue
+		}
+		seen[req.SKU] = rowNum
+
+		batch = append(batch, importRow{num: rowNum, req: req})
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	if !dryRun {
+		if err := tx.Commit(ctx); err != nil {
+			return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		s.productRepo.InvalidateCache(ctx)
+	}
+
+	return report, nil
+}
+
+// upsertBatch пишет батч под точкой сохранения. Если база отвергла какую-то
+// строку, батч откатывается и строки пишутся по одной, каждая под своей
+// точкой сохранения: отвергнутые попадают в отчёт, остальные записываются.
+func (s *productCSVService) upsertBatch(ctx context.Context, tx pgx.Tx, batch []importRow, report *models.ImportReport) error {
+	reqs := make([]*models.ImportProduct, len(batch))
+	for i, row := range batch {
+		reqs[i] = row.req
+	}
+
+	statuses, err := upsertInSavepoint(ctx, tx, s.productRepo, reqs)
+	if err == nil {
+		for i, status := range statuses {
+			recordStatus(report, batch[i], status)
+		}
+		return nil
+	}
+	if !isRowError(err) {
+		return err
+	}
+
+	for _, row := range batch {
+		statuses, err := upsertInSavepoint(ctx, tx, s.productRepo, []*models.ImportProduct{row.req})
+		if err != nil {
+			if !isRowError(err) {
+				return err
+			}
+			report.AddError(row.num, row.req.SKU, err.Error())
+			continue
+		}
+		recordStatus(report, row, statuses[0])
+	}
+	return nil
+}
+
+// recordStatus учитывает результат строки; удалённый товар — ошибка строки,
+// чтобы администратор видел, что импорт его не восстановил.
+func recordStatus(report *models.ImportReport, row importRow, status string) {
+	if status == models.ImportDeleted {
+		report.AddError(row.num, row.req.SKU, "product with this sku was deleted, it is not restored by import")
+		return
+	}
+	report.Count(status)
+}
+
+func upsertInSavepoint(ctx context.Context, tx pgx.Tx, repo repositories.ProductRepository, reqs []*models.ImportProduct) ([]string, error) {
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer sp.Rollback(ctx)
+
+	statuses, err := repo.UpsertBySKU(ctx, sp, reqs)
+	if err != nil {
+		return nil, err
+	}
+	return statuses, sp.Commit(ctx)
+}
+
+// isRowError отличает ошибку данных строки (нарушенное ограничение,
+// переполнение числа) от проблем с соединением, при которых импорт прерывается.
+func isRowError(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr)
+}
+
+func (s *productCSVService) Export(ctx context.Context, w io.Writer, delimiter rune) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = delimiter
+
+	if err := writer.Write(productCSVHeader); err != nil {
+		return err
+	}
+
+	err := s.productRepo.ForEach(ctx, func(p *models.Product) error {
+		return writer.Write([]string{
+			p.SKU,
+			p.Name,
+			p.Description,
+			strconv.FormatFloat(p.Price, 'f', 2, 64),
+			strconv.Itoa(p.Inventory),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// parseProductRecord собирает товар из строки и проверяет его теми же
+// правилами binding, что и POST /products. Необязательные колонки, которых
+// нет в файле, и пустой остаток не меняют существующий товар.
+func parseProductRecord(record []string, columns map[string]int) (*models.ImportProduct, error) {
+	field := func(name string) (string, bool) {
+		i, ok := columns[name]
+		if !ok {
+			return "", false
+		}
+		if i >= len(record) {
+			return "", true
+		}
+		return strings.TrimSpace(record[i]), true
+	}
+
+	sku, _ := field("sku")
+	name, _ := field("name")
+	product := &models.ImportProduct{SKU: sku, Name: name}
+	req := &models.CreateProductRequest{SKU: sku, Name: name}
+
+	if product.SKU == "" {
+		return product, errors.New("sku is required")
+	}
+
+	if description, ok := field("description"); ok {
+		product.Description = &description
+		req.Description = description
+	}
+
+	// Выгрузки из Excel с русской локалью используют запятую как разделитель дробной части.
+	rawPrice, _ := field("price")
+	price, err := strconv.ParseFloat(strings.Replace(rawPrice, ",", ".", 1), 64)
+	if err != nil || math.IsInf(price, 0) || math.IsNaN(price) {
+		return product, fmt.Errorf("invalid price %q", rawPrice)
+	}
+	product.Price = price
+	req.Price = price
+
+	if v, _ := field("inventory"); v != "" {
+		inventory, err := strconv.Atoi(v)
+		if err != nil {
+			return product, fmt.Errorf("invalid inventory %q", v)
+		}
+		product.Inventory = &inventory
+		req.Inventory = inventory
+	}
+
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return product, err
+	}
+
+	return product, nil
+}
@@ -4,20 +4,41 @@ import (
 	"context"
 	"ecommerce-api/internal/models"
 	"ecommerce-api/internal/repositories"
+	"fmt"
 )
 
 type ProductService interface {
 	CreateProduct(ctx context.Context, req *models.CreateProductRequest) (int64, error)
-	GetProducts(ctx context.Context) ([]*models.Product, error)
+	GetProducts(ctx context.Context, params *models.ProductListParams) (*models.ProductListResponse, error)
+	GetProduct(ctx context.Context, id int64) (*models.Product, error)
+	UpdateProduct(ctx context.Context, id int64, req *models.UpdateProductRequest) (*models.Product, error)
+	DeleteProduct(ctx context.Context, id int64) error
+	SearchProducts(ctx context.Context, params *models.ProductSearchParams) ([]*models.ProductSearchResult, error)
+	SetPurchaseRules(ctx context.Context, id int64, req *models.SetPurchaseRulesRequest) (*models.PurchaseRules, error)
+	DeletePurchaseRules(ctx context.Context, id int64) error
 }
 
 type productService struct {
-	repo repositories.ProductRepository
+	repo          repositories.ProductRepository
+	variantRepo   repositories.VariantRepository
+	attributeRepo repositories.AttributeRepository
+	ruleRepo      repositories.PurchaseRuleRepository
+	imageSvc      ImageService
 }
 
-func NewProductService(repo repositories.ProductRepository) ProductService {
+func NewProductService(
+	repo repositories.ProductRepository,
+	variantRepo repositories.VariantRepository,
+	attributeRepo repositories.AttributeRepository,
+	ruleRepo repositories.PurchaseRuleRepository,
+	imageSvc ImageService,
+) ProductService {
 	return &productService{
-		repo: repo,
+		repo:          repo,
+		variantRepo:   variantRepo,
+		attributeRepo: attributeRepo,
+		ruleRepo:      ruleRepo,
+		imageSvc:      imageSvc,
 	}
 }
 
@@ -25,6 +46,123 @@ func (ps *productService) CreateProduct(ctx context.Context, req *models.CreateP
 	return ps.repo.Create(ctx, req)
 }
 
-func (ps *productService) GetProducts(ctx context.Context) ([]*models.Product, error) {
-	return ps.repo.List(ctx)
+func (ps *productService) GetProducts(ctx context.Context, params *models.ProductListParams) (*models.ProductListResponse, error) {
+	if len(params.Attributes) > 0 {
+		attributes, err := ps.attributeRepo.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := resolveAttributeFilters(attributeDefinitions(attributes), params.Attributes); err != nil {
+			return nil, err
+		}
+	}
+
+	response, err := ps.repo.List(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ps.attachImages(ctx, response.Items); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (ps *productService) GetProduct(ctx context.Context, id int64) (*models.Product, error) {
+	product, err := ps.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	product.Options, err = ps.variantRepo.ListOptions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	product.Variants, err = ps.variantRepo.ListByProduct(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ps.attachImages(ctx, []*models.Product{product}); err != nil {
+		return nil, err
+	}
+
+	product.PurchaseRules, err = ps.ruleRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
+func (ps *productService) UpdateProduct(ctx context.Context, id int64, req *models.UpdateProductRequest) (*models.Product, error) {
+	return ps.repo.Update(ctx, id, req)
+}
+
+func (ps *productService) DeleteProduct(ctx context.Context, id int64) error {
+	return ps.repo.Delete(ctx, id)
+}
+
+func (ps *productService) SearchProducts(ctx context.Context, params *models.ProductSearchParams) ([]*models.ProductSearchResult, error) {
+	return ps.repo.Search(ctx, params.Query, params.Limit)
+}
+
+// SetPurchaseRules заменяет правила покупки товара целиком; незаданные
+// минимум и шаг означают 1.
+func (ps *productService) SetPurchaseRules(ctx context.Context, id int64, req *models.SetPurchaseRulesRequest) (*models.PurchaseRules, error) {
+	rules := &models.PurchaseRules{
+		MinQuantity:       max(req.MinQuantity, 1),
+		MaxQuantity:       req.MaxQuantity,
+		QuantityStep:      max(req.QuantityStep, 1),
+		CustomerLimit:     req.CustomerLimit,
+		CustomerLimitDays: req.CustomerLimitDays,
+	}
+
+	switch {
+	case rules.MinQuantity%rules.QuantityStep != 0:
+		return nil, fmt.Errorf("%w: min_quantity must be a multiple of quantity_step", ErrInvalidPurchaseRules)
+	case rules.MaxQuantity != nil && *rules.MaxQuantity < rules.MinQuantity:
+		return nil, fmt.Errorf("%w: max_quantity must not be less than min_quantity", ErrInvalidPurchaseRules)
+	case (rules.CustomerLimit == nil) != (rules.CustomerLimitDays == nil):
+		return nil, fmt.Errorf("%w: customer_limit and customer_limit_days must be set together", ErrInvalidPurchaseRules)
+	case rules.CustomerLimit != nil && *rules.CustomerLimit < rules.MinQuantity:
+		return nil, fmt.Errorf("%w: customer_limit must not be less than min_quantity", ErrInvalidPurchaseRules)
+	}
+
+	if err := ps.ruleRepo.Set(ctx, id, rules); err != nil {
+		return nil, err
+	}
+	ps.repo.InvalidateCache(ctx, id)
+	return rules, nil
+}
+
+func (ps *productService) DeletePurchaseRules(ctx context.Context, id int64) error {
+	if err := ps.ruleRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	ps.repo.InvalidateCache(ctx, id)
+	return nil
+}
+
+func (ps *productService) attachImages(ctx context.Context, products []*models.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(products))
+	for _, p := range products {
+		ids = append(ids, p.ID)
+	}
+
+	images, err := ps.imageSvc.ListByProducts(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range products {
+		p.Images = images[p.ID]
+	}
+	return nil
 }
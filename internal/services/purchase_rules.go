@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"ecommerce-api/internal/models"
+	"ecommerce-api/internal/repositories"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidPurchaseRules = errors.New("invalid purchase rules")
+
+// PurchaseRuleError — нарушение правила покупки товара. Отдаётся клиенту
+// как есть: по Code интерфейс показывает подсказку.
+type PurchaseRuleError struct {
+	Code      string `json:"code"`
+	ProductID int64  `json:"product_id"`
+	VariantID *int64 `json:"variant_id,omitempty"`
+	Message   string `json:"error"`
+	// Limit — значение нарушенного правила.
+	Limit int `json:"limit"`
+	// Allowed — наибольшее количество, допустимое в этой позиции, для
+	// max_quantity и customer_limit.
+	Allowed *int `json:"allowed,omitempty"`
+}
+
+func (e *PurchaseRuleError) Error() string {
+	return e.Message
+}
+
+// checkPurchaseRules проверяет позицию item с количеством quantity по правилам
+// товара. others — сколько штук других вариантов того же товара лежит рядом
+// в корзине или заказе. Лимит на покупателя проверяется только при userID != 0:
+// у гостя нет истории заказов, для него он проверится при оформлении.
+func checkPurchaseRules(
+	ctx context.Context,
+	ruleRepo repositories.PurchaseRuleRepository,
+	rules *models.PurchaseRules,
+	userID int64,
+	item models.CartItemKey,
+	quantity int,
+	others int,
+) error {
+	if rules == nil {
+		return nil
+	}
+
+	ruleErr := &PurchaseRuleError{ProductID: item.ProductID}
+	if item.VariantID != 0 {
+		ruleErr.VariantID = &item.VariantID
+	}
+
+	switch {
+	case quantity%rules.QuantityStep != 0:
+		ruleErr.Code = models.PurchaseRuleQuantityStep
+		ruleErr.Limit = rules.QuantityStep
+		ruleErr.Message = fmt.Sprintf("product is sold in packs of %d", rules.QuantityStep)
+		return ruleErr
+	case quantity < rules.MinQuantity:
+		ruleErr.Code = models.PurchaseRuleMinQuantity
+		ruleErr.Limit = rules.MinQuantity
+		ruleErr.Message = fmt.Sprintf("minimum order quantity is %d", rules.MinQuantity)
+		return ruleErr
+	case rules.MaxQuantity != nil && others+quantity > *rules.MaxQuantity:
+		allowed := max(*rules.MaxQuantity-others, 0)
+		ruleErr.Code = models.PurchaseRuleMaxQuantity
+		ruleErr.Limit = *rules.MaxQuantity
+		ruleErr.Allowed = &allowed
+		ruleErr.Message = fmt.Sprintf("at most %d items of this product per order", *rules.MaxQuantity)
+		return ruleErr
+	}
+
+	if rules.CustomerLimit == nil || userID == 0 {
+		return nil
+	}
+
+	since := time.Now().AddDate(0, 0, -*rules.CustomerLimitDays)
+	purchased, err := ruleRepo.PurchasedQuantity(ctx, userID, item.ProductID, since)
+	if err != nil {
+		return err
+	}
+
+	if purchased+others+quantity > *rules.CustomerLimit {
+		allowed := max(*rules.CustomerLimit-purchased-others, 0)
+		ruleErr.Code = models.PurchaseRuleCustomerLimit
+		ruleErr.Limit = *rules.CustomerLimit
+		ruleErr.Allowed = &allowed
+		ruleErr.Message = fmt.Sprintf("at most %d items of this product per customer in %d days, %d already ordered",
+			*rules.CustomerLimit, *rules.CustomerLimitDays, purchased)
+		return ruleErr
+	}
+	return nil
+}
+
+// otherVariantsQuantity — сколько штук других позиций того же товара в корзине.
+func otherVariantsQuantity(cart map[models.CartItemKey]models.CartLine, item models.CartItemKey) int {
+	var total int
+	for key, line := range cart {
+		if key.ProductID == item.ProductID && key != item {
+			total += line.Quantity
+		}
+	}
+	return total
+}
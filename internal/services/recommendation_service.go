@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"ecommerce-api/internal/models"
+	"ecommerce-api/internal/repositories"
+	"errors"
+	"log"
+	"sort"
+	"time"
+)
+
+const (
+	defaultRelatedLimit    = 10
+	maxCartRecommendations = 20
+	copurchaseMinOrders    = 2
+	copurchasePerProduct   = 50
+)
+
+var ErrInvalidRelatedProduct = errors.New("product cannot be related to itself")
+
+type RecommendationService interface {
+	Related(ctx context.Context, productID int64, limit int) ([]*models.RelatedProduct, error)
+	ForProducts(ctx context.Context, productIDs []int64, limit int) ([]*models.RelatedProduct, error)
+	SetRelated(ctx context.Context, productID int64, req *models.SetRelatedProductsRequest) error
+	RunRefresher(ctx context.Context, interval time.Duration)
+}
+
+type recommendationService struct {
+	productRepo repositories.ProductRepository
+	relatedRepo repositories.RelatedRepository
+	imageSvc    ImageService
+}
+
+func NewRecommendationService(
+	productRepo repositories.ProductRepository,
+	relatedRepo repositories.RelatedRepository,
+	imageSvc ImageService,
+) RecommendationService {
+	return &recommendationService{
+		productRepo: productRepo,
+		relatedRepo: relatedRepo,
+		imageSvc:    imageSvc,
+	}
+}
+
+func (s *recommendationService) Related(ctx context.Context, productID int64, limit int) ([]*models.RelatedProduct, error) {
+	if _, err := s.productRepo.GetByID(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = defaultRelatedLimit
+	}
+
+	return s.ForProducts(ctx, []int64{productID}, limit)
+}
+
+// ForProducts сначала отдаёт связи, заданные вручную, затем товары, которые
+// чаще всего покупали вместе с productIDs. Для нескольких исходных товаров
+// счётчики совместных покупок суммируются. Сами productIDs в выдачу не попадают.
+func (s *recommendationService) ForProducts(ctx context.Context, productIDs []int64, limit int) ([]*models.RelatedProduct, error) {
+	if len(productIDs) == 0 || limit <= 0 {
+		return []*models.RelatedProduct{}, nil
+	}
+
+	exclude := make(map[int64]bool, len(productIDs))
+	for _, id := range productIDs {
+		exclude[id] = true
+	}
+
+	links, err := s.relatedRepo.ListLinks(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	copurchases, err := s.relatedRepo.ListCopurchases(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int64
+	sources := make(map[int64]string)
+	for _, l := range links {
+		if exclude[l.RelatedID] || sources[l.RelatedID] != "" {
+			continue
+		}
+		sources[l.RelatedID] = models.RelatedCurated
+		ids = append(ids, l.RelatedID)
+	}
+
+	scores := make(map[int64]int)
+	var bought []int64
+	for _, l := range copurchases {
+		if exclude[l.RelatedID] || sources[l.RelatedID] == models.RelatedCurated {
+			continue
+		}
+		if _, ok := scores[l.RelatedID]; !ok {
+			bought = append(bought, l.RelatedID)
+		}
+		scores[l.RelatedID] += l.Score
+	}
+	sort.SliceStable(bought, func(i, j int) bool {
+		return scores[bought[i]] > scores[bought[j]]
+	})
+	for _, id := range bought {
+		sources[id] = models.RelatedBoughtTogether
+		ids = append(ids, id)
+	}
+
+	// Берём с запасом: удалённые товары GetByIDs отфильтрует.
+	if len(ids) > limit*2 {
+		ids = ids[:limit*2]
+	}
+
+	products, err := s.productRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]*models.Product, len(products))
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+
+	related := make([]*models.RelatedProduct, 0, limit)
+	for _, id := range ids {
+		p, ok := byID[id]
+		if !ok {
+			continue
+		}
+		related = append(related, &models.RelatedProduct{Product: *p, Source: sources[id]})
+		if len(related) == limit {
+			break
+		}
+	}
+
+	found := make([]*models.Product, len(related))
+	for i, r := range related {
+		found[i] = &r.Product
+	}
+	if err := s.attachImages(ctx, found); err != nil {
+		return nil, err
+	}
+
+	return related, nil
+}
+
+func (s *recommendationService) SetRelated(ctx context.Context, productID int64, req *models.SetRelatedProductsRequest) error {
+	if _, err := s.productRepo.GetByID(ctx, productID); err != nil {
+		return err
+	}
+
+	seen := make(map[int64]bool, len(req.ProductIDs))
+	relatedIDs := make([]int64, 0, len(req.ProductIDs))
+	for _, id := range req.ProductIDs {
+		if id == productID {
+			return ErrInvalidRelatedProduct
+		}
+		if !seen[id] {
+			seen[id] = true
+			relatedIDs = append(relatedIDs, id)
+		}
+	}
+
+	return s.relatedRepo.SetLinks(ctx, productID, relatedIDs)
+}
+
+// RunRefresher пересчитывает совместные покупки раз в interval, пока не
+// отменён ctx.
+func (s *recommendationService) RunRefresher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		pairs, err := s.relatedRepo.RefreshCopurchases(ctx, copurchaseMinOrders, copurchasePerProduct)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			log.Printf("co-purchase refresh error: %v", err)
+		case err == nil:
+			log.Printf("co-purchase refresh: %d pairs", pairs)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *recommendationService) attachImages(ctx context.Context, products []*models.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(products))
+	for _, p := range products {
+		ids = append(ids, p.ID)
+	}
+
+	images, err := s.imageSvc.ListByProducts(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range products {
+		p.Images = images[p.ID]
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"ecommerce-api/internal/models"
+	"ecommerce-api/internal/repositories"
+	"errors"
+)
+
+const defaultReviewPageSize = 20
+
+var ErrNotVerifiedBuyer = errors.New("only customers who bought this product can review it")
+
+type ReviewService interface {
+	CreateReview(ctx context.Context, userID int64, productID int64, req *models.CreateReviewRequest) (*models.Review, error)
+	ListProductReviews(ctx context.Context, productID int64, params *models.ReviewListParams) (*models.ReviewListResponse, error)
+	ListForModeration(ctx context.Context, params *models.ModerationListParams) (*models.ReviewListResponse, error)
+	Approve(ctx context.Context, id int64) (*models.Review, error)
+	Reject(ctx context.Context, id int64) (*models.Review, error)
+}
+
+type reviewService struct {
+	productRepo repositories.ProductRepository
+	reviewRepo  repositories.ReviewRepository
+}
+
+func NewReviewService(productRepo repositories.ProductRepository, reviewRepo repositories.ReviewRepository) ReviewService {
+	return &reviewService{
+		productRepo: productRepo,
+		reviewRepo:  reviewRepo,
+	}
+}
+
+// CreateReview принимает отзыв только от покупателя с оплаченным заказом.
+// Отзыв попадает на модерацию и в рейтинг не входит, пока его не одобрят.
+func (s *reviewService) CreateReview(ctx context.Context, userID int64, productID int64, req *models.CreateReviewRequest) (*models.Review, error) {
+	if _, err := s.productRepo.GetByID(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	purchased, err := s.reviewRepo.HasPurchased(ctx, userID, productID)
+	if err != nil {
+		return nil, err
+	}
+	if !purchased {
+		return nil, ErrNotVerifiedBuyer
+	}
+
+	review := &models.Review{
+		ProductID: productID,
+		UserID:    userID,
+		Rating:    req.Rating,
+		Body:      req.Body,
+	}
+	if err := s.reviewRepo.Create(ctx, review); err != nil {
+		return nil, err
+	}
+
+	return review, nil
+}
+
+func (s *reviewService) ListProductReviews(ctx context.Context, productID int64, params *models.ReviewListParams) (*models.ReviewListResponse, error) {
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultReviewPageSize
+	}
+
+	reviews, err := s.reviewRepo.ListApproved(ctx, productID, params.After, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	return newReviewListResponse(reviews, limit), nil
+}
+
+func (s *reviewService) ListForModeration(ctx context.Context, params *models.ModerationListParams) (*models.ReviewListResponse, error) {
+	status := params.Status
+	if status == "" {
+		status = models.ReviewPending
+	}
+
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultReviewPageSize
+	}
+
+	reviews, err := s.reviewRepo.ListByStatus(ctx, status, params.After, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	return newReviewListResponse(reviews, limit), nil
+}
+
+func (s *reviewService) Approve(ctx context.Context, id int64) (*models.Review, error) {
+	return s.moderate(ctx, id, models.ReviewApproved)
+}
+
+func (s *reviewService) Reject(ctx context.Context, id int64) (*models.Review, error) {
+	return s.moderate(ctx, id, models.ReviewRejected)
+}
+
+// moderate меняет статус отзыва; рейтинг товара при этом пересчитывается
+// в базе, поэтому товар сбрасывается из кэша.
+func (s *reviewService) moderate(ctx context.Context, id int64, status string) (*models.Review, error) {
+	review, err := s.reviewRepo.SetStatus(ctx, id, status)
+	if err != nil {
+		return nil, err
+	}
+
+	s.productRepo.InvalidateCache(ctx, review.ProductID)
+	return review, nil
+}
+
+func newReviewListResponse(reviews []*models.Review, limit int) *models.ReviewListResponse {
+	response := &models.ReviewListResponse{Items: reviews}
+	if len(reviews) > limit {
+		response.Items = reviews[:limit]
+		response.NextAfter = reviews[limit-1].ID
+	}
+	return response
+}
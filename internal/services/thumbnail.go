@@ -0,0 +1,56 @@
+package services
+
+import (
+	"image"
+	"image/color"
+)
+
+// resizeToFit уменьшает картинку так, чтобы большая сторона была не больше
+// maxSide. Каждый пиксель результата — среднее по соответствующему блоку
+// исходника, этого достаточно для превью без внешних зависимостей.
+func resizeToFit(src image.Image, maxSide int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxSide && h <= maxSide {
+		return src
+	}
+
+	nw, nh := maxSide, maxSide
+	if w > h {
+		nh = max(1, h*maxSide/w)
+	} else {
+		nw = max(1, w*maxSide/h)
+	}
+
+	dst := image.NewRGBA64(image.Rect(0, 0, nw, nh))
+	for y := 0; y < nh; y++ {
+		sy0 := b.Min.Y + y*h/nh
+		sy1 := max(sy0+1, b.Min.Y+(y+1)*h/nh)
+
+		for x := 0; x < nw; x++ {
+			sx0 := b.Min.X + x*w/nw
+			sx1 := max(sx0+1, b.Min.X+(x+1)*w/nw)
+
+			var r, g, bl, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r += uint64(pr)
+					g += uint64(pg)
+					bl += uint64(pb)
+					a += uint64(pa)
+					n++
+				}
+			}
+
+			dst.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(bl / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+
+	return dst
+}
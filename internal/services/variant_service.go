@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"ecommerce-api/internal/models"
+	"ecommerce-api/internal/repositories"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var ErrInvalidVariantOptions = errors.New("invalid variant options")
+
+type VariantService interface {
+	SetOptions(ctx context.Context, productID int64, req *models.SetProductOptionsRequest) error
+	CreateVariant(ctx context.Context, productID int64, req *models.CreateVariantRequest) (int64, error)
+	UpdateVariant(ctx context.Context, productID int64, variantID int64, req *models.UpdateVariantRequest) (*models.ProductVariant, error)
+	DeleteVariant(ctx context.Context, productID int64, variantID int64) error
+}
+
+type variantService struct {
+	productRepo repositories.ProductRepository
+	variantRepo repositories.VariantRepository
+}
+
+func NewVariantService(productRepo repositories.ProductRepository, variantRepo repositories.VariantRepository) VariantService {
+	return &variantService{
+		productRepo: productRepo,
+		variantRepo: variantRepo,
+	}
+}
+
+func (s *variantService) SetOptions(ctx context.Context, productID int64, req *models.SetProductOptionsRequest) error {
+	if _, err := s.productRepo.GetByID(ctx, productID); err != nil {
+		return err
+	}
+
+	names := make(map[string]bool, len(req.Options))
+	for _, o := range req.Options {
+		if names[o.Name] {
+			return fmt.Errorf("%w: duplicate option %q", ErrInvalidVariantOptions, o.Name)
+		}
+		names[o.Name] = true
+	}
+
+	if err := s.variantRepo.SetOptions(ctx, productID, req.Options); err != nil {
+		return err
+	}
+
+	s.productRepo.InvalidateCache(ctx, productID)
+	return nil
+}
+
+// CreateVariant требует, чтобы у варианта было значение для каждой опции
+// товара и чтобы значения входили в объявленный список.
+func (s *variantService) CreateVariant(ctx context.Context, productID int64, req *models.CreateVariantRequest) (int64, error) {
+	if _, err := s.productRepo.GetByID(ctx, productID); err != nil {
+		return 0, err
+	}
+
+	options, err := s.variantRepo.ListOptions(ctx, productID)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(req.Options) != len(options) {
+		return 0, fmt.Errorf("%w: expected values for %d options", ErrInvalidVariantOptions, len(options))
+	}
+
+	for _, o := range options {
+		value, ok := req.Options[o.Name]
+		if !ok {
+			return 0, fmt.Errorf("%w: missing option %q", ErrInvalidVariantOptions, o.Name)
+		}
+		if !slices.Contains(o.Values, value) {
+			return 0, fmt.Errorf("%w: %q is not a valid value for %q", ErrInvalidVariantOptions, value, o.Name)
+		}
+	}
+
+	id, err := s.variantRepo.Create(ctx, productID, req)
+	if err != nil {
+		return 0, err
+	}
+
+	s.productRepo.InvalidateCache(ctx, productID)
+	return id, nil
+}
+
+func (s *variantService) UpdateVariant(ctx context.Context, productID int64, variantID int64, req *models.UpdateVariantRequest) (*models.ProductVariant, error) {
+	variant, err := s.variantRepo.Update(ctx, productID, variantID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	s.productRepo.InvalidateCache(ctx, productID)
+	return variant, nil
+}
+
+func (s *variantService) DeleteVariant(ctx context.Context, productID int64, variantID int64) error {
+	if err := s.variantRepo.Delete(ctx, productID, variantID); err != nil {
+		return err
+	}
+
+	s.productRepo.InvalidateCache(ctx, productID)
+	return nil
+}
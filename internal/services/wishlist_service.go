@@ -0,0 +1,294 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"ecommerce-api/internal/models"
+	"ecommerce-api/internal/repositories"
+	"encoding/base64"
+	"errors"
+)
+
+var (
+	ErrNotInCart     = errors.New("product is not in the cart")
+	ErrNotInWishlist = errors.New("product is not in the wishlist")
+)
+
+type WishlistService interface {
+	List(ctx context.Context, userID int64) ([]*models.Wishlist, error)
+	Create(ctx context.Context, userID int64, req *models.CreateWishlistRequest) (*models.Wishlist, error)
+	// Get и GetShared возвращают список с позициями по текущим ценам и остаткам.
+	Get(ctx context.Context, userID int64, id int64) (*models.Wishlist, error)
+	GetShared(ctx context.Context, token string) (*models.Wishlist, error)
+	Update(ctx context.Context, userID int64, id int64, req *models.UpdateWishlistRequest) (*models.Wishlist, error)
+	Delete(ctx context.Context, userID int64, id int64) error
+	AddItem(ctx context.Context, userID int64, id int64, item models.CartItemKey, quantity int) error
+	RemoveItem(ctx context.Context, userID int64, id int64, item models.CartItemKey) error
+	// MoveToCart кладёт товар из списка в корзину с учётом остатка и убирает
+	// из списка то количество, которое поместилось в корзину.
+	MoveToCart(ctx context.Context, userID int64, id int64, item models.CartItemKey) (total int, capped bool, err error)
+	// SaveForLater переносит позицию корзины в список wishlistID, а при
+	// wishlistID = 0 — в список «Отложено». Возвращает id списка.
+	SaveForLater(ctx context.Context, userID int64, item models.CartItemKey, wishlistID int64) (int64, error)
+}
+
+type wishlistService struct {
+	wishlistRepo repositories.WishlistRepository
+	productRepo  repositories.ProductRepository
+	variantRepo  repositories.VariantRepository
+	cartRepo     repositories.CartRepository
+	cartSvc      CartService
+	imageSvc     ImageService
+}
+
+func NewWishlistService(
+	wishlistRepo repositories.WishlistRepository,
+	productRepo repositories.ProductRepository,
+	variantRepo repositories.VariantRepository,
+	cartRepo repositories.CartRepository,
+	cartSvc CartService,
+	imageSvc ImageService,
+) WishlistService {
+	return &wishlistService{
+		wishlistRepo: wishlistRepo,
+		productRepo:  productRepo,
+		variantRepo:  variantRepo,
+		cartRepo:     cartRepo,
+		cartSvc:      cartSvc,
+		imageSvc:     imageSvc,
+	}
+}
+
+func (s *wishlistService) List(ctx context.Context, userID int64) ([]*models.Wishlist, error) {
+	return s.wishlistRepo.ListByUser(ctx, userID)
+}
+
+func (s *wishlistService) Create(ctx context.Context, userID int64, req *models.CreateWishlistRequest) (*models.Wishlist, error) {
+	wishlist := &models.Wishlist{UserID: userID, Name: req.Name}
+	if err := s.wishlistRepo.Create(ctx, wishlist); err != nil {
+		return nil, err
+	}
+	return wishlist, nil
+}
+
+func (s *wishlistService) Get(ctx context.Context, userID int64, id int64) (*models.Wishlist, error) {
+	wishlist, err := s.wishlistRepo.GetByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return wishlist, s.loadItems(ctx, wishlist)
+}
+
+func (s *wishlistService) GetShared(ctx context.Context, token string) (*models.Wishlist, error) {
+	wishlist, err := s.wishlistRepo.GetByShareToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return wishlist, s.loadItems(ctx, wishlist)
+}
+
+func (s *wishlistService) Update(ctx context.Context, userID int64, id int64, req *models.UpdateWishlistRequest) (*models.Wishlist, error) {
+	wishlist, err := s.wishlistRepo.GetByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		wishlist.Name = *req.Name
+	}
+
+	// Повторная публикация сохраняет прежнюю ссылку, а снятие с публикации
+	// её отзывает: после новой публикации старая ссылка уже не откроется.
+	if req.Public != nil {
+		switch {
+		case *req.Public && wishlist.ShareToken == nil:
+			token, err := newShareToken()
+			if err != nil {
+				return nil, err
+			}
+			wishlist.ShareToken = &token
+		case !*req.Public:
+			wishlist.ShareToken = nil
+		}
+	}
+
+	if err := s.wishlistRepo.Update(ctx, wishlist); err != nil {
+		return nil, err
+	}
+	return wishlist, nil
+}
+
+func (s *wishlistService) Delete(ctx context.Context, userID int64, id int64) error {
+	return s.wishlistRepo.Delete(ctx, id, userID)
+}
+
+func (s *wishlistService) AddItem(ctx context.Context, userID int64, id int64, item models.CartItemKey, quantity int) error {
+	wishlist, err := s.wishlistRepo.GetByID(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+
+	// В список можно отложить и товар, которого нет в наличии, но не
+	// несуществующий товар или вариант.
+	if _, _, err := resolveItem(ctx, s.productRepo, s.variantRepo, item); err != nil {
+		return err
+	}
+
+	return s.wishlistRepo.AddItem(ctx, wishlist.ID, item, max(quantity, 1))
+}
+
+func (s *wishlistService) RemoveItem(ctx context.Context, userID int64, id int64, item models.CartItemKey) error {
+	wishlist, err := s.wishlistRepo.GetByID(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+
+	removed, err := s.wishlistRepo.RemoveItem(ctx, wishlist.ID, item)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrNotInWishlist
+	}
+	return nil
+}
+
+func (s *wishlistService) MoveToCart(ctx context.Context, userID int64, id int64, item models.CartItemKey) (int, bool, error) {
+	wishlist, err := s.wishlistRepo.GetByID(ctx, id, userID)
+	if err != nil {
+		return 0, false, err
+	}
+
+	quantity, err := s.wishlistRepo.GetItemQuantity(ctx, wishlist.ID, item)
+	if err != nil {
+		return 0, false, err
+	}
+	if quantity == 0 {
+		return 0, false, ErrNotInWishlist
+	}
+
+	owner := models.UserCart(userID)
+	cart, _, err := s.cartRepo.GetCart(ctx, owner)
+	if err != nil {
+		return 0, false, err
+	}
+	before := cart[item].Quantity
+
+	// Если товара нет в наличии, AddItem вернёт ошибку и товар останется в списке.
+	total, capped, err := s.cartSvc.AddItem(ctx, owner, item, quantity)
+	if err != nil {
+		return 0, false, err
+	}
+
+	// Урезанное остатком или правилами покупки количество остаётся в списке:
+	// из него уходит только то, что действительно попало в корзину.
+	moved := total - before
+	if moved > 0 {
+		if err := s.wishlistRepo.TakeItem(ctx, wishlist.ID, item, moved); err != nil {
+			return 0, false, err
+		}
+	}
+	return total, capped, nil
+}
+
+func (s *wishlistService) SaveForLater(ctx context.Context, userID int64, item models.CartItemKey, wishlistID int64) (int64, error) {
+	owner := models.UserCart(userID)
+	cart, _, err := s.cartRepo.GetCart(ctx, owner)
+	if err != nil {
+		return 0, err
+	}
+	line, ok := cart[item]
+	if !ok {
+		return 0, ErrNotInCart
+	}
+
+	var wishlist *models.Wishlist
+	if wishlistID == 0 {
+		wishlist, err = s.wishlistRepo.GetDefault(ctx, userID)
+	} else {
+		wishlist, err = s.wishlistRepo.GetByID(ctx, wishlistID, userID)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// Сначала список, потом корзина: при сбое между шагами товар окажется
+	// в обоих местах, а не пропадёт.
+	if err := s.wishlistRepo.AddItem(ctx, wishlist.ID, item, line.Quantity); err != nil {
+		return 0, err
+	}
+	if err := s.cartRepo.RemoveItem(ctx, owner, item); err != nil {
+		return 0, err
+	}
+	return wishlist.ID, nil
+}
+
+// loadItems подставляет в позиции списка текущие цену, остаток и картинку.
+func (s *wishlistService) loadItems(ctx context.Context, wishlist *models.Wishlist) error {
+	items, err := s.wishlistRepo.ListItems(ctx, wishlist.ID)
+	if err != nil {
+		return err
+	}
+	wishlist.Items = items
+	if len(items) == 0 {
+		return nil
+	}
+
+	keys := make(map[models.CartItemKey]models.CartLine, len(items))
+	productIDs := make([]int64, 0, len(items))
+	for _, item := range items {
+		key := models.CartItemKey{ProductID: item.ProductID}
+		if item.VariantID != nil {
+			key.VariantID = *item.VariantID
+		}
+		keys[key] = models.CartLine{Quantity: item.Quantity}
+		productIDs = append(productIDs, item.ProductID)
+	}
+
+	productMap, variantMap, err := loadCartItems(ctx, s.productRepo, s.variantRepo, keys)
+	if err != nil {
+		return err
+	}
+
+	imageURLs, err := s.imageSvc.MainImageURLs(ctx, productIDs)
+	if err != nil {
+		return err
+	}
+
+	for i := range wishlist.Items {
+		item := &wishlist.Items[i]
+		product, ok := productMap[item.ProductID]
+		if !ok {
+			continue
+		}
+
+		item.Name = product.Name
+		item.ImageURL = imageURLs[product.ID]
+		if item.VariantID == nil {
+			item.Price = product.Price
+			item.Inventory = product.Inventory
+		} else {
+			variant, ok := variantMap[*item.VariantID]
+			if !ok || variant.ProductID != product.ID {
+				continue
+			}
+			item.SKU = variant.SKU
+			item.Options = variant.Options
+			item.Price = variant.PriceFor(product)
+			item.Inventory = variant.Inventory
+		}
+
+		item.Available = true
+		item.InStock = item.Inventory > 0
+	}
+
+	return nil
+}
+
+func newShareToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,12 @@
+package storage
+
+import (
+	"context"
+	"io"
+)
+
+type BlobStorage interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_products_not_deleted;
+
+ALTER TABLE products DROP COLUMN IF EXISTS deleted_at;
\ No newline at end of file
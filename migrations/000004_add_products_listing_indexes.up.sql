@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS idx_products_price_id ON products(price, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_products_created_at_id ON products(created_at, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_products_name_id ON products(name, id) WHERE deleted_at IS NULL;
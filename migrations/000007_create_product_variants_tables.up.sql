@@ -0,0 +1,24 @@
+CREATE TABLE product_options (
+    id BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    values TEXT[] NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (product_id, name)
+);
+
+CREATE TABLE product_variants (
+    id BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    sku VARCHAR(100) UNIQUE NOT NULL,
+    price NUMERIC(10,2) CHECK (price >= 0),
+    inventory INTEGER NOT NULL CHECK (inventory >= 0) DEFAULT 0,
+    options JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_variants_product_id ON product_variants(product_id) WHERE deleted_at IS NULL;
+
+ALTER TABLE order_items ADD COLUMN variant_id BIGINT REFERENCES product_variants(id);
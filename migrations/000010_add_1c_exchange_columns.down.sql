@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_orders_not_exported;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS exported_at;
+
+ALTER TABLE products DROP COLUMN IF EXISTS external_id;
\ No newline at end of file
@@ -0,0 +1,5 @@
+ALTER TABLE products ADD COLUMN external_id VARCHAR(100) UNIQUE;
+
+ALTER TABLE orders ADD COLUMN exported_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_orders_not_exported ON orders(id) WHERE exported_at IS NULL;
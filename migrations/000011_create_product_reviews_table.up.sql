@@ -0,0 +1,17 @@
+CREATE TABLE product_reviews (
+    id BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    body TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    moderated_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (product_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_reviews_product_approved ON product_reviews(product_id, id) WHERE status = 'approved';
+CREATE INDEX IF NOT EXISTS idx_product_reviews_status ON product_reviews(status, id);
+
+ALTER TABLE products ADD COLUMN rating_sum INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN rating_count INTEGER NOT NULL DEFAULT 0;
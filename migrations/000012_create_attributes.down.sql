@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_products_attributes;
+
+ALTER TABLE products DROP COLUMN IF EXISTS attributes;
+
+DROP TABLE IF EXISTS attributes;
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS scheduled_prices;
+
+DROP TRIGGER IF EXISTS products_price_update ON products;
+DROP TRIGGER IF EXISTS products_price_insert ON products;
+DROP FUNCTION IF EXISTS record_product_price();
+
+DROP TABLE IF EXISTS product_prices;
@@ -0,0 +1,43 @@
+CREATE TABLE product_prices (
+    id BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    price NUMERIC(10,2) NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_prices_product_id ON product_prices(product_id, changed_at DESC);
+
+INSERT INTO product_prices (product_id, price, changed_at)
+SELECT id, price, created_at FROM products;
+
+-- История пишется триггером, чтобы её не обходил ни один путь записи:
+-- PATCH, CSV-импорт, обмен с 1С и планировщик цен.
+CREATE OR REPLACE FUNCTION record_product_price() RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO product_prices (product_id, price) VALUES (NEW.id, NEW.price);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER products_price_insert
+AFTER INSERT ON products
+FOR EACH ROW EXECUTE FUNCTION record_product_price();
+
+CREATE TRIGGER products_price_update
+AFTER UPDATE OF price ON products
+FOR EACH ROW
+WHEN (OLD.price IS DISTINCT FROM NEW.price)
+EXECUTE FUNCTION record_product_price();
+
+CREATE TABLE scheduled_prices (
+    id BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    price NUMERIC(10,2) NOT NULL CHECK (price > 0),
+    apply_at TIMESTAMPTZ NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'applied', 'canceled')),
+    applied_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_prices_due ON scheduled_prices(apply_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_scheduled_prices_product_id ON scheduled_prices(product_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS product_copurchases;
+
+DROP TABLE IF EXISTS product_links;
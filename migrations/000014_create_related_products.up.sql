@@ -0,0 +1,17 @@
+CREATE TABLE product_links (
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    related_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (product_id, related_id),
+    CHECK (product_id <> related_id)
+);
+
+CREATE TABLE product_copurchases (
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    related_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    orders_count INTEGER NOT NULL,
+    PRIMARY KEY (product_id, related_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_copurchases_rank ON product_copurchases(product_id, orders_count DESC);
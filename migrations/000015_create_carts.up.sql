@@ -0,0 +1,18 @@
+-- Корзины в Postgres используются, когда Redis не настроен или недоступен.
+-- id совпадает с владельцем корзины: "user:<id>" или "guest:<guest_id>".
+CREATE TABLE carts (
+    id TEXT PRIMARY KEY,
+    user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE cart_items (
+    cart_id TEXT NOT NULL REFERENCES carts(id) ON DELETE CASCADE,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id BIGINT NOT NULL DEFAULT 0,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    price NUMERIC(10,2) NOT NULL DEFAULT 0,
+    PRIMARY KEY (cart_id, product_id, variant_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_carts_updated_at ON carts(updated_at);
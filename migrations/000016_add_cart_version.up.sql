@@ -0,0 +1,3 @@
+-- Версия растёт при каждом изменении корзины; заказ очищает корзину,
+-- только если она не менялась с момента чтения.
+ALTER TABLE carts ADD COLUMN version BIGINT NOT NULL DEFAULT 0;
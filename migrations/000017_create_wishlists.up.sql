@@ -0,0 +1,23 @@
+CREATE TABLE wishlists (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    -- Список «Отложено», куда товары попадают из корзины; у пользователя он один.
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    -- NULL — список не опубликован.
+    share_token VARCHAR(64) UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_wishlists_user ON wishlists(user_id, id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_wishlists_user_default ON wishlists(user_id) WHERE is_default;
+
+CREATE TABLE wishlist_items (
+    wishlist_id BIGINT NOT NULL REFERENCES wishlists(id) ON DELETE CASCADE,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id BIGINT NOT NULL DEFAULT 0,
+    quantity INTEGER NOT NULL DEFAULT 1 CHECK (quantity > 0),
+    added_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (wishlist_id, product_id, variant_id)
+);
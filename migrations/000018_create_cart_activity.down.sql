@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS cart_reminders;
+
+DROP TABLE IF EXISTS cart_activity;
@@ -0,0 +1,11 @@
+-- Время последнего изменения корзины пользователя — по нему ищутся брошенные
+-- корзины. Хранится в Postgres, потому что сами корзины могут жить в Redis.
+CREATE TABLE cart_activity (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    reminded_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_cart_activity_updated_at ON cart_activity(updated_at);
+
+ALTER TABLE users ADD COLUMN cart_reminders BOOLEAN NOT NULL DEFAULT TRUE;
@@ -0,0 +1,11 @@
+-- Правила покупки товара. Товар без строки здесь покупается штучно и без ограничений.
+CREATE TABLE product_purchase_rules (
+    product_id BIGINT PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    min_quantity INTEGER NOT NULL DEFAULT 1 CHECK (min_quantity >= 1),
+    max_quantity INTEGER CHECK (max_quantity >= min_quantity),
+    quantity_step INTEGER NOT NULL DEFAULT 1 CHECK (quantity_step >= 1),
+    customer_limit INTEGER CHECK (customer_limit > 0),
+    customer_limit_days INTEGER CHECK (customer_limit_days > 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK ((customer_limit IS NULL) = (customer_limit_days IS NULL))
+);
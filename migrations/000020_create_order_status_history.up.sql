@@ -0,0 +1,23 @@
+CREATE TABLE order_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    -- NULL у записи о создании заказа.
+    from_status VARCHAR(32),
+    to_status VARCHAR(32) NOT NULL,
+    -- Что вызвало переход: customer, payment_webhook, system, admin.
+    source VARCHAR(32) NOT NULL,
+    actor_id BIGINT,
+    comment TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_status_history_order ON order_status_history(order_id, id);
+
+-- У заказов, созданных до появления истории, известен только текущий статус.
+INSERT INTO order_status_history (order_id, to_status, source, created_at)
+SELECT id, status, 'system', created_at FROM orders;
+
+-- NOT VALID: старые строки не проверяются, новые статусы — только из списка.
+ALTER TABLE orders ADD CONSTRAINT orders_status_check
+    CHECK (status IN ('pending', 'awaiting_payment', 'paid', 'processing', 'shipped', 'delivered', 'canceled', 'refunded'))
+    NOT VALID;
@@ -0,0 +1,6 @@
+UPDATE products
+SET deleted_at = NULL,
+    updated_at = NOW()
+WHERE awaiting_offer;
+
+ALTER TABLE products DROP COLUMN IF EXISTS awaiting_offer;
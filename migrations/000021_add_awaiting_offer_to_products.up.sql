@@ -0,0 +1,10 @@
+-- Товар из import.xml скрыт (deleted_at) до первой цены из offers.xml.
+-- Флаг отличает такое скрытие от удаления администратором.
+ALTER TABLE products ADD COLUMN awaiting_offer BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Цену 0 товару можно было задать только синхронизацией каталога.
+UPDATE products
+SET deleted_at = NOW(),
+    awaiting_offer = TRUE,
+    updated_at = NOW()
+WHERE external_id IS NOT NULL AND price = 0 AND deleted_at IS NULL;
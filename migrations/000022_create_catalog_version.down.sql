@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS product_categories_catalog_version ON product_categories;
+DROP TRIGGER IF EXISTS categories_catalog_version ON categories;
+DROP TRIGGER IF EXISTS products_catalog_version ON products;
+DROP FUNCTION IF EXISTS bump_catalog_version();
+
+DROP TABLE IF EXISTS catalog_version;
@@ -0,0 +1,39 @@
+-- Счётчик изменений каталога для кэша фида. MAX(updated_at) для этого не
+-- годится: NOW() — время начала транзакции, и транзакция, закоммиченная
+-- позже, может записать время меньше уже прочитанного максимума.
+CREATE TABLE catalog_version (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    version BIGINT NOT NULL DEFAULT 0,
+    -- Транзакция, которая последней увеличила версию: одна транзакция
+    -- увеличивает её один раз, сколько бы строк ни поменяла.
+    last_xact BIGINT
+);
+
+INSERT INTO catalog_version DEFAULT VALUES;
+
+CREATE OR REPLACE FUNCTION bump_catalog_version() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE catalog_version
+    SET version = version + 1,
+        last_xact = txid_current()
+    WHERE last_xact IS DISTINCT FROM txid_current();
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Отложенные до коммита триггеры держат блокировку строки счётчика только
+-- на время коммита, и версии растут в порядке коммитов.
+CREATE CONSTRAINT TRIGGER products_catalog_version
+AFTER INSERT OR UPDATE OR DELETE ON products
+DEFERRABLE INITIALLY DEFERRED
+FOR EACH ROW EXECUTE FUNCTION bump_catalog_version();
+
+CREATE CONSTRAINT TRIGGER categories_catalog_version
+AFTER INSERT OR UPDATE OR DELETE ON categories
+DEFERRABLE INITIALLY DEFERRED
+FOR EACH ROW EXECUTE FUNCTION bump_catalog_version();
+
+CREATE CONSTRAINT TRIGGER product_categories_catalog_version
+AFTER INSERT OR UPDATE OR DELETE ON product_categories
+DEFERRABLE INITIALLY DEFERRED
+FOR EACH ROW EXECUTE FUNCTION bump_catalog_version();
@@ -0,0 +1 @@
+DROP TRIGGER IF EXISTS product_variants_catalog_version ON product_variants;
@@ -0,0 +1,6 @@
+-- Варианты попадают в фид отдельными предложениями, поэтому их изменения
+-- тоже должны сбрасывать кэш фида.
+CREATE CONSTRAINT TRIGGER product_variants_catalog_version
+AFTER INSERT OR UPDATE OR DELETE ON product_variants
+DEFERRABLE INITIALLY DEFERRED
+FOR EACH ROW EXECUTE FUNCTION bump_catalog_version();